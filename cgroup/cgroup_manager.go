@@ -1,13 +1,19 @@
 package cgroup
 
 import (
+	"errors"
+	"fmt"
 	"mini-docker/cgroup/subsystems"
+	"path/filepath"
 
 	"go.uber.org/zap"
 )
 
+// the parent cgroup of all containers
+const DefaultCgroupRoot = "mini-docker"
+
 type CgroupManager struct {
-	Path string 
+	Path     string
 	Resource subsystems.ResourceConfig
 }
 
@@ -17,18 +23,46 @@ func NewCgroupManager(path string) *CgroupManager {
 	}
 }
 
+// ContainerCgroupPath return the cgroup path of the container
+// cgroup-path = mini-docker/{container-id}
+func ContainerCgroupPath(containerID string) string {
+	return filepath.Join(DefaultCgroupRoot, containerID)
+}
+
+// ListContainerCgroups list the container ids which own a cgroup
+func ListContainerCgroups() []string {
+	return subsystems.ListChildren(DefaultCgroupRoot)
+}
+
+// Apply move the process into the cgroup, the errors of the subsystems whose
+// limits are set by Set are joined, the others are only logged
 func (c *CgroupManager) Apply(pid int) error {
+	var errs []error
 	for _, sub := range subsystems.SubSystems {
-		sub.Apply(c.Path, pid)
+		if err := sub.Apply(c.Path, pid); err != nil {
+			zap.L().Sugar().Warnf("apply cgroup %s failed %v", sub.Name(), err)
+			if c.Resource.Requires(sub.Name()) {
+				errs = append(errs, fmt.Errorf("apply cgroup %s failed %v", sub.Name(), err))
+			}
+		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
+// Set write the limits to all the subsystems, the errors of the subsystems
+// whose limits are set are joined, the others are only logged
 func (c *CgroupManager) Set(cfg *subsystems.ResourceConfig) error {
+	c.Resource = *cfg
+	var errs []error
 	for _, sub := range subsystems.SubSystems {
-		sub.Set(c.Path, cfg)
+		if err := sub.Set(c.Path, cfg); err != nil {
+			zap.L().Sugar().Warnf("set cgroup %s failed %v", sub.Name(), err)
+			if cfg.Requires(sub.Name()) {
+				errs = append(errs, fmt.Errorf("set cgroup %s failed %v", sub.Name(), err))
+			}
+		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 func (c *CgroupManager) Destroy() error {
@@ -38,4 +72,40 @@ func (c *CgroupManager) Destroy() error {
 		}
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// Stats collect the resource usage of the cgroup from all the subsystems
+func (c *CgroupManager) Stats() (*subsystems.Stats, error) {
+	stats := &subsystems.Stats{}
+	for _, sub := range subsystems.SubSystems {
+		if err := sub.Stat(c.Path, stats); err != nil {
+			zap.L().Sugar().Warnf("get cgroup %s stats failed %v", sub.Name(), err)
+		}
+	}
+	return stats, nil
+}
+
+// Freeze suspend(frozen is true) or resume all the processes of the cgroup
+func (c *CgroupManager) Freeze(frozen bool) error {
+	for _, sub := range subsystems.SubSystems {
+		if freezer, ok := sub.(subsystems.FreezerSubSystem); ok {
+			return freezer.Freeze(c.Path, frozen)
+		}
+	}
+	return fmt.Errorf("cgroup freezer isn't supported")
+}
+
+// OOMKilled report whether the oom killer killed any process of the cgroup,
+// which is counted in memory.oom_control(v1) or memory.events(v2)
+func (c *CgroupManager) OOMKilled() bool {
+	stats := &subsystems.Stats{}
+	for _, sub := range subsystems.SubSystems {
+		if sub.Name() != "memory" {
+			continue
+		}
+		if err := sub.Stat(c.Path, stats); err != nil {
+			zap.L().Sugar().Warnf("get cgroup memory stats failed %v", err)
+		}
+	}
+	return stats.OOMKills > 0
+}
@@ -0,0 +1,94 @@
+package subsystems
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type blkioSubSystem struct{}
+
+func (b *blkioSubSystem) Name() string {
+	return "blkio"
+}
+
+func (b *blkioSubSystem) Set(cgroupPath string, cfg *ResourceConfig) error {
+	if subsysCgroupPath, err := getCgroupPath(b.Name(), cgroupPath, true); err != nil {
+		return err
+	} else {
+		throttles := []struct {
+			file   string
+			limits []string
+			bytes  bool
+		}{
+			{"blkio.throttle.read_bps_device", cfg.DeviceReadBps, true},
+			{"blkio.throttle.write_bps_device", cfg.DeviceWriteBps, true},
+			{"blkio.throttle.read_iops_device", cfg.DeviceReadIOps, false},
+			{"blkio.throttle.write_iops_device", cfg.DeviceWriteIOps, false},
+		}
+		for _, throttle := range throttles {
+			devices, err := parseDeviceLimits(throttle.limits, throttle.bytes)
+			if err != nil {
+				return err
+			}
+			// one device per write, e.g. "8:0 1048576"
+			for _, device := range devices {
+				value := fmt.Sprintf("%d:%d %d", device.major, device.minor, device.rate)
+				if err := writeCgroupFile(subsysCgroupPath, throttle.file, value); err != nil {
+					return fmt.Errorf("set cgroup blkio fail %v", err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (b *blkioSubSystem) Apply(cgroupPath string, pid int) error {
+	if subsysCgroupPath, err := getCgroupPath(b.Name(), cgroupPath, false); err != nil {
+		return err
+	} else {
+		if err = os.WriteFile(filepath.Join(subsysCgroupPath, "tasks"), []byte(fmt.Sprintf("%d", pid)), 0644); err != nil {
+			return fmt.Errorf("set cgroup proc fail %v", err)
+		}
+	}
+	return nil
+}
+
+func (b *blkioSubSystem) Remove(cgroupPath string) error {
+	return removeCgroupPath(b.Name(), cgroupPath)
+}
+
+// blkio.throttle.io_service_bytes is formatted as "8:0 Read 4096" per line
+func (b *blkioSubSystem) Stat(cgroupPath string, stats *Stats) error {
+	subsysCgroupPath, err := getCgroupPath(b.Name(), cgroupPath, false)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(filepath.Join(subsysCgroupPath, "blkio.throttle.io_service_bytes"))
+	if err != nil {
+		return fmt.Errorf("get cgroup blkio fail %v", err)
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		fields := strings.Fields(scan.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[1] {
+		case "Read":
+			stats.BlockRead += v
+		case "Write":
+			stats.BlockWrite += v
+		}
+	}
+	return scan.Err()
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 )
 
 type cpuSubSystem struct{}
@@ -20,10 +21,21 @@ func (c *cpuSubSystem) Set(cgroupPath string, cfg *ResourceConfig) error {
 			if err = os.WriteFile(filepath.Join(subsysCgroupPath, "cpu.shares"), []byte(cfg.CpuShare), 0644); err != nil {
 				return fmt.Errorf("set cgroup cpu fail %v", err)
 			}
-			return nil
+		}
+		if cfg.Cpus != "" {
+			quota, period, err := ParseCPUs(cfg.Cpus)
+			if err != nil {
+				return err
+			}
+			if err := writeCgroupFile(subsysCgroupPath, "cpu.cfs_period_us", strconv.FormatUint(period, 10)); err != nil {
+				return fmt.Errorf("set cgroup cpu fail %v", err)
+			}
+			if err := writeCgroupFile(subsysCgroupPath, "cpu.cfs_quota_us", strconv.FormatUint(quota, 10)); err != nil {
+				return fmt.Errorf("set cgroup cpu fail %v", err)
+			}
 		}
 	}
-	return fmt.Errorf("set cgroup cpu error")
+	return nil
 }
 
 func (c *cpuSubSystem) Apply(cgroupPath string, pid int) error {
@@ -38,9 +50,10 @@ func (c *cpuSubSystem) Apply(cgroupPath string, pid int) error {
 }
 
 func (c *cpuSubSystem) Remove(cgroupPath string) error {
-	if subsysCgroupPath, err := getCgroupPath(c.Name(), cgroupPath, false); err != nil {
-		return err
-	} else {
-		return os.RemoveAll(filepath.Join(subsysCgroupPath, cgroupPath))
-	}
+	return removeCgroupPath(c.Name(), cgroupPath)
+}
+
+// the cpu usage is accounted by cpuacct in cgroup v1
+func (c *cpuSubSystem) Stat(cgroupPath string, stats *Stats) error {
+	return nil
 }
@@ -0,0 +1,83 @@
+package subsystems
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// cpu controller of the unified hierarchy
+type cpuV2SubSystem struct{}
+
+func (c *cpuV2SubSystem) Name() string {
+	return "cpu"
+}
+
+func (c *cpuV2SubSystem) Set(cgroupPath string, cfg *ResourceConfig) error {
+	if subsysCgroupPath, err := getCgroupV2Path(cgroupPath, true); err != nil {
+		return err
+	} else {
+		if cfg.Requires(c.Name()) {
+			if err := enableController(cgroupPath, c.Name()); err != nil {
+				return err
+			}
+		}
+		if cfg.CpuShare != "" {
+			weight, err := convertCPUSharesToWeight(cfg.CpuShare)
+			if err != nil {
+				return err
+			}
+			if err = os.WriteFile(filepath.Join(subsysCgroupPath, "cpu.weight"), []byte(strconv.FormatUint(weight, 10)), 0644); err != nil {
+				return fmt.Errorf("set cgroup cpu fail %v", err)
+			}
+		}
+		if cfg.Cpus != "" {
+			quota, period, err := ParseCPUs(cfg.Cpus)
+			if err != nil {
+				return err
+			}
+			if err := writeCgroupFile(subsysCgroupPath, "cpu.max", fmt.Sprintf("%d %d", quota, period)); err != nil {
+				return fmt.Errorf("set cgroup cpu fail %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *cpuV2SubSystem) Apply(cgroupPath string, pid int) error {
+	return applyCgroupV2(cgroupPath, pid)
+}
+
+func (c *cpuV2SubSystem) Remove(cgroupPath string) error {
+	return removeCgroupV2Path(cgroupPath)
+}
+
+// cpu.shares is in [2, 262144] and cpu.weight is in [1, 10000]
+// reference: https://github.com/opencontainers/runc/blob/main/libcontainer/cgroups/utils.go
+func convertCPUSharesToWeight(cpuShare string) (uint64, error) {
+	shares, err := strconv.ParseUint(cpuShare, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cpu share %s", cpuShare)
+	}
+	if shares < 2 {
+		shares = 2
+	} else if shares > 262144 {
+		shares = 262144
+	}
+	return 1 + ((shares-2)*9999)/262142, nil
+}
+
+func (c *cpuV2SubSystem) Stat(cgroupPath string, stats *Stats) error {
+	subsysCgroupPath, err := getCgroupV2Path(cgroupPath, false)
+	if err != nil {
+		return err
+	}
+	cpuStat, err := readKeyValue(filepath.Join(subsysCgroupPath, "cpu.stat"))
+	if err != nil {
+		return fmt.Errorf("get cgroup cpu stat fail %v", err)
+	}
+	// usage_usec is in microseconds
+	stats.CpuUsage = cpuStat["usage_usec"] * 1000
+	return nil
+}
@@ -0,0 +1,45 @@
+package subsystems
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// cpuacct only accounts the cpu usage, it may be mounted without cpu
+type cpuAcctSubSystem struct{}
+
+func (c *cpuAcctSubSystem) Name() string {
+	return "cpuacct"
+}
+
+func (c *cpuAcctSubSystem) Set(cgroupPath string, cfg *ResourceConfig) error {
+	_, err := getCgroupPath(c.Name(), cgroupPath, true)
+	return err
+}
+
+func (c *cpuAcctSubSystem) Apply(cgroupPath string, pid int) error {
+	if subsysCgroupPath, err := getCgroupPath(c.Name(), cgroupPath, false); err != nil {
+		return err
+	} else {
+		if err = os.WriteFile(filepath.Join(subsysCgroupPath, "tasks"), []byte(fmt.Sprintf("%d", pid)), 0644); err != nil {
+			return fmt.Errorf("set cgroup proc fail %v", err)
+		}
+	}
+	return nil
+}
+
+func (c *cpuAcctSubSystem) Remove(cgroupPath string) error {
+	return removeCgroupPath(c.Name(), cgroupPath)
+}
+
+func (c *cpuAcctSubSystem) Stat(cgroupPath string, stats *Stats) error {
+	subsysCgroupPath, err := getCgroupPath(c.Name(), cgroupPath, false)
+	if err != nil {
+		return err
+	}
+	if stats.CpuUsage, err = readUint(filepath.Join(subsysCgroupPath, "cpuacct.usage")); err != nil {
+		return fmt.Errorf("get cgroup cpuacct usage fail %v", err)
+	}
+	return nil
+}
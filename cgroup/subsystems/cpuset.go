@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type cpuSetSubSystem struct{}
@@ -16,14 +17,16 @@ func (c *cpuSetSubSystem) Set(cgroupPath string, cfg *ResourceConfig) error {
 	if subsysCgroupPath, err := getCgroupPath(c.Name(), cgroupPath, true); err != nil {
 		return err
 	} else {
-		if cfg.CpuShare != "" {
-			if err = os.WriteFile(filepath.Join(subsysCgroupPath, "cpuset.cpus"), []byte(cfg.CpuShare), 0644); err != nil {
+		if err := initCpuset(findCgroupMountPoint(c.Name()), cgroupPath); err != nil {
+			return fmt.Errorf("init cgroup cpuset fail %v", err)
+		}
+		if cfg.CpuSet != "" {
+			if err = os.WriteFile(filepath.Join(subsysCgroupPath, "cpuset.cpus"), []byte(cfg.CpuSet), 0644); err != nil {
 				return fmt.Errorf("set cgroup cpuset fail %v", err)
 			}
-			return nil
 		}
 	}
-	return fmt.Errorf("set cgroup cpuset error")
+	return nil
 }
 
 func (c *cpuSetSubSystem) Apply(cgroupPath string, pid int) error {
@@ -38,9 +41,35 @@ func (c *cpuSetSubSystem) Apply(cgroupPath string, pid int) error {
 }
 
 func (c *cpuSetSubSystem) Remove(cgroupPath string) error {
-	if subsysCgroupPath, err := getCgroupPath(c.Name(), cgroupPath, false); err != nil {
-		return err
-	} else {
-		return os.RemoveAll(filepath.Join(subsysCgroupPath, cgroupPath))
+	return removeCgroupPath(c.Name(), cgroupPath)
+}
+
+// a new cpuset cgroup has empty cpuset.cpus and cpuset.mems, tasks can't
+// join it until both are set, so inherit them from the parent level by level
+func initCpuset(root, cgroupPath string) error {
+	parent := root
+	for _, dir := range strings.Split(filepath.Clean(cgroupPath), string(filepath.Separator)) {
+		current := filepath.Join(parent, dir)
+		for _, file := range []string{"cpuset.cpus", "cpuset.mems"} {
+			value, err := os.ReadFile(filepath.Join(current, file))
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(string(value)) != "" {
+				continue
+			}
+			if value, err = os.ReadFile(filepath.Join(parent, file)); err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(current, file), value, 0644); err != nil {
+				return err
+			}
+		}
+		parent = current
 	}
+	return nil
+}
+
+func (c *cpuSetSubSystem) Stat(cgroupPath string, stats *Stats) error {
+	return nil
 }
@@ -0,0 +1,44 @@
+package subsystems
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// cpuset controller of the unified hierarchy
+type cpuSetV2SubSystem struct{}
+
+func (c *cpuSetV2SubSystem) Name() string {
+	return "cpuset"
+}
+
+func (c *cpuSetV2SubSystem) Set(cgroupPath string, cfg *ResourceConfig) error {
+	if subsysCgroupPath, err := getCgroupV2Path(cgroupPath, true); err != nil {
+		return err
+	} else {
+		if cfg.Requires(c.Name()) {
+			if err := enableController(cgroupPath, c.Name()); err != nil {
+				return err
+			}
+		}
+		if cfg.CpuSet != "" {
+			if err = os.WriteFile(filepath.Join(subsysCgroupPath, "cpuset.cpus"), []byte(cfg.CpuSet), 0644); err != nil {
+				return fmt.Errorf("set cgroup cpuset fail %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *cpuSetV2SubSystem) Apply(cgroupPath string, pid int) error {
+	return applyCgroupV2(cgroupPath, pid)
+}
+
+func (c *cpuSetV2SubSystem) Remove(cgroupPath string) error {
+	return removeCgroupV2Path(cgroupPath)
+}
+
+func (c *cpuSetV2SubSystem) Stat(cgroupPath string, stats *Stats) error {
+	return nil
+}
@@ -0,0 +1,72 @@
+package subsystems
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type freezerSubSystem struct{}
+
+func (f *freezerSubSystem) Name() string {
+	return "freezer"
+}
+
+func (f *freezerSubSystem) Set(cgroupPath string, cfg *ResourceConfig) error {
+	_, err := getCgroupPath(f.Name(), cgroupPath, true)
+	return err
+}
+
+func (f *freezerSubSystem) Apply(cgroupPath string, pid int) error {
+	if subsysCgroupPath, err := getCgroupPath(f.Name(), cgroupPath, false); err != nil {
+		return err
+	} else {
+		if err = os.WriteFile(filepath.Join(subsysCgroupPath, "tasks"), []byte(fmt.Sprintf("%d", pid)), 0644); err != nil {
+			return fmt.Errorf("set cgroup proc fail %v", err)
+		}
+	}
+	return nil
+}
+
+func (f *freezerSubSystem) Remove(cgroupPath string) error {
+	return removeCgroupPath(f.Name(), cgroupPath)
+}
+
+func (f *freezerSubSystem) Stat(cgroupPath string, stats *Stats) error {
+	return nil
+}
+
+// freezer.state passes through FREEZING before it becomes FROZEN
+func (f *freezerSubSystem) Freeze(cgroupPath string, frozen bool) error {
+	subsysCgroupPath, err := getCgroupPath(f.Name(), cgroupPath, false)
+	if err != nil {
+		return err
+	}
+	state := "THAWED"
+	if frozen {
+		state = "FROZEN"
+	}
+	if err := writeCgroupFile(subsysCgroupPath, "freezer.state", state); err != nil {
+		return fmt.Errorf("set cgroup freezer fail %v", err)
+	}
+	return waitCgroupFile(filepath.Join(subsysCgroupPath, "freezer.state"), func(content string) bool {
+		return strings.TrimSpace(content) == state
+	})
+}
+
+// wait until the content of the cgroup file meets the condition
+func waitCgroupFile(path string, condition func(content string) bool) error {
+	for i := 0; i < 1000; i++ {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if condition(string(content)) {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return fmt.Errorf("wait for %s timeout", path)
+}
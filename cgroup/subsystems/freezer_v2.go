@@ -0,0 +1,49 @@
+package subsystems
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// cgroup.freeze is a core file of the unified hierarchy, it isn't a controller
+type freezerV2SubSystem struct{}
+
+func (f *freezerV2SubSystem) Name() string {
+	return "freezer"
+}
+
+func (f *freezerV2SubSystem) Set(cgroupPath string, cfg *ResourceConfig) error {
+	_, err := getCgroupV2Path(cgroupPath, true)
+	return err
+}
+
+func (f *freezerV2SubSystem) Apply(cgroupPath string, pid int) error {
+	return applyCgroupV2(cgroupPath, pid)
+}
+
+func (f *freezerV2SubSystem) Remove(cgroupPath string) error {
+	return removeCgroupV2Path(cgroupPath)
+}
+
+func (f *freezerV2SubSystem) Stat(cgroupPath string, stats *Stats) error {
+	return nil
+}
+
+// the cgroup is frozen when cgroup.events reports "frozen 1"
+func (f *freezerV2SubSystem) Freeze(cgroupPath string, frozen bool) error {
+	subsysCgroupPath, err := getCgroupV2Path(cgroupPath, false)
+	if err != nil {
+		return err
+	}
+	state := "0"
+	if frozen {
+		state = "1"
+	}
+	if err := writeCgroupFile(subsysCgroupPath, "cgroup.freeze", state); err != nil {
+		return fmt.Errorf("set cgroup freeze fail %v", err)
+	}
+	return waitCgroupFile(filepath.Join(subsysCgroupPath, "cgroup.events"), func(content string) bool {
+		events, _ := parseKeyValue(content)
+		return fmt.Sprint(events["frozen"]) == state
+	})
+}
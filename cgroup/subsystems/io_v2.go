@@ -0,0 +1,99 @@
+package subsystems
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// io controller of the unified hierarchy, the successor of blkio
+type ioV2SubSystem struct{}
+
+func (i *ioV2SubSystem) Name() string {
+	return "io"
+}
+
+func (i *ioV2SubSystem) Set(cgroupPath string, cfg *ResourceConfig) error {
+	if subsysCgroupPath, err := getCgroupV2Path(cgroupPath, true); err != nil {
+		return err
+	} else {
+		if cfg.Requires(i.Name()) {
+			if err := enableController(cgroupPath, i.Name()); err != nil {
+				return err
+			}
+		}
+		throttles := []struct {
+			key    string
+			limits []string
+			bytes  bool
+		}{
+			{"rbps", cfg.DeviceReadBps, true},
+			{"wbps", cfg.DeviceWriteBps, true},
+			{"riops", cfg.DeviceReadIOps, false},
+			{"wiops", cfg.DeviceWriteIOps, false},
+		}
+		for _, throttle := range throttles {
+			devices, err := parseDeviceLimits(throttle.limits, throttle.bytes)
+			if err != nil {
+				return err
+			}
+			// io.max is keyed by device, e.g. "8:0 rbps=1048576"
+			for _, device := range devices {
+				value := fmt.Sprintf("%d:%d %s=%d", device.major, device.minor, throttle.key, device.rate)
+				if err := writeCgroupFile(subsysCgroupPath, "io.max", value); err != nil {
+					return fmt.Errorf("set cgroup io fail %v", err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (i *ioV2SubSystem) Apply(cgroupPath string, pid int) error {
+	return applyCgroupV2(cgroupPath, pid)
+}
+
+func (i *ioV2SubSystem) Remove(cgroupPath string) error {
+	return removeCgroupV2Path(cgroupPath)
+}
+
+// io.stat is formatted as "8:0 rbytes=4096 wbytes=0 rios=1 wios=0 ..." per line
+func (i *ioV2SubSystem) Stat(cgroupPath string, stats *Stats) error {
+	subsysCgroupPath, err := getCgroupV2Path(cgroupPath, false)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(filepath.Join(subsysCgroupPath, "io.stat"))
+	if err != nil {
+		return fmt.Errorf("get cgroup io fail %v", err)
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		fields := strings.Fields(scan.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			v, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch kv[0] {
+			case "rbytes":
+				stats.BlockRead += v
+			case "wbytes":
+				stats.BlockWrite += v
+			}
+		}
+	}
+	return scan.Err()
+}
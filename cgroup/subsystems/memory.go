@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 )
 
 type memorySubSystem struct{}
@@ -17,13 +18,53 @@ func (m *memorySubSystem) Set(cgroupPath string, cfg *ResourceConfig) error {
 		return err
 	} else {
 		if cfg.MemoryLimit != "" {
-			if err = os.WriteFile(filepath.Join(subsysCgroupPath, "memory.limit_in_bytes"), []byte(cfg.MemoryLimit), 0644); err != nil {
+			memory, err := ParseBytes(cfg.MemoryLimit)
+			if err != nil {
+				return err
+			}
+			if err := setMemoryLimit(subsysCgroupPath, memory, cfg.MemorySwap); err != nil {
+				return fmt.Errorf("set cgroup memory fail %v", err)
+			}
+		}
+		if cfg.MemoryReservation != "" {
+			reservation, err := ParseBytes(cfg.MemoryReservation)
+			if err != nil {
+				return err
+			}
+			if err := writeCgroupFile(subsysCgroupPath, "memory.soft_limit_in_bytes", strconv.FormatUint(reservation, 10)); err != nil {
 				return fmt.Errorf("set cgroup memory fail %v", err)
 			}
-			return nil
 		}
 	}
-	return fmt.Errorf("set cgroup memory error")
+	return nil
+}
+
+// memory.memsw.limit_in_bytes must not be less than memory.limit_in_bytes,
+// so the order of writing depends on whether the limit grows
+func setMemoryLimit(subsysCgroupPath string, memory uint64, memorySwap string) error {
+	limit := strconv.FormatUint(memory, 10)
+	if memorySwap == "" {
+		return writeCgroupFile(subsysCgroupPath, "memory.limit_in_bytes", limit)
+	}
+	swap, err := parseMemorySwap(memorySwap)
+	if err != nil {
+		return err
+	}
+	memsw := strconv.FormatInt(swap, 10)
+	current, err := readUint(filepath.Join(subsysCgroupPath, "memory.memsw.limit_in_bytes"))
+	if err != nil {
+		return fmt.Errorf("swap accounting isn't enabled %v", err)
+	}
+	if swap == -1 || uint64(swap) > current {
+		if err := writeCgroupFile(subsysCgroupPath, "memory.memsw.limit_in_bytes", memsw); err != nil {
+			return err
+		}
+		return writeCgroupFile(subsysCgroupPath, "memory.limit_in_bytes", limit)
+	}
+	if err := writeCgroupFile(subsysCgroupPath, "memory.limit_in_bytes", limit); err != nil {
+		return err
+	}
+	return writeCgroupFile(subsysCgroupPath, "memory.memsw.limit_in_bytes", memsw)
 }
 
 func (m *memorySubSystem) Apply(cgroupPath string, pid int) error {
@@ -38,9 +79,28 @@ func (m *memorySubSystem) Apply(cgroupPath string, pid int) error {
 }
 
 func (m *memorySubSystem) Remove(cgroupPath string) error {
-	if subsysCgroupPath, err := getCgroupPath(m.Name(), cgroupPath, false); err != nil {
+	return removeCgroupPath(m.Name(), cgroupPath)
+}
+
+func (m *memorySubSystem) Stat(cgroupPath string, stats *Stats) error {
+	subsysCgroupPath, err := getCgroupPath(m.Name(), cgroupPath, false)
+	if err != nil {
 		return err
-	} else {
-		return os.RemoveAll(filepath.Join(subsysCgroupPath, cgroupPath))
 	}
+	if stats.MemoryUsage, err = readUint(filepath.Join(subsysCgroupPath, "memory.usage_in_bytes")); err != nil {
+		return fmt.Errorf("get cgroup memory usage fail %v", err)
+	}
+	if stats.MemoryPeak, err = readUint(filepath.Join(subsysCgroupPath, "memory.max_usage_in_bytes")); err != nil {
+		return fmt.Errorf("get cgroup memory peak fail %v", err)
+	}
+	if stats.MemoryLimit, err = readUint(filepath.Join(subsysCgroupPath, "memory.limit_in_bytes")); err != nil {
+		return fmt.Errorf("get cgroup memory limit fail %v", err)
+	}
+	// oom_kill is only provided since linux 4.13
+	oomControl, err := readKeyValue(filepath.Join(subsysCgroupPath, "memory.oom_control"))
+	if err != nil {
+		return fmt.Errorf("get cgroup memory oom control fail %v", err)
+	}
+	stats.OOMKills = oomControl["oom_kill"]
+	return nil
 }
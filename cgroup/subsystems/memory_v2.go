@@ -0,0 +1,90 @@
+package subsystems
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+)
+
+// memory controller of the unified hierarchy
+type memoryV2SubSystem struct{}
+
+func (m *memoryV2SubSystem) Name() string {
+	return "memory"
+}
+
+func (m *memoryV2SubSystem) Set(cgroupPath string, cfg *ResourceConfig) error {
+	if subsysCgroupPath, err := getCgroupV2Path(cgroupPath, true); err != nil {
+		return err
+	} else {
+		// stats reads memory.current, so the controller is also enabled without limits,
+		// but only the container with limits fails if it can't be enabled
+		if err := enableController(cgroupPath, m.Name()); err != nil && cfg.Requires(m.Name()) {
+			return err
+		}
+		if cfg.MemoryLimit != "" {
+			memory, err := ParseBytes(cfg.MemoryLimit)
+			if err != nil {
+				return err
+			}
+			if err := writeCgroupFile(subsysCgroupPath, "memory.max", strconv.FormatUint(memory, 10)); err != nil {
+				return fmt.Errorf("set cgroup memory fail %v", err)
+			}
+			// memory.swap.max only limits the swap, not the total of memory and swap
+			if cfg.MemorySwap != "" {
+				swap, err := parseMemorySwap(cfg.MemorySwap)
+				if err != nil {
+					return err
+				}
+				swapMax := "max"
+				if swap != -1 {
+					swapMax = strconv.FormatUint(uint64(swap)-memory, 10)
+				}
+				if err := writeCgroupFile(subsysCgroupPath, "memory.swap.max", swapMax); err != nil {
+					return fmt.Errorf("set cgroup memory swap fail %v", err)
+				}
+			}
+		}
+		if cfg.MemoryReservation != "" {
+			reservation, err := ParseBytes(cfg.MemoryReservation)
+			if err != nil {
+				return err
+			}
+			if err := writeCgroupFile(subsysCgroupPath, "memory.low", strconv.FormatUint(reservation, 10)); err != nil {
+				return fmt.Errorf("set cgroup memory fail %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *memoryV2SubSystem) Apply(cgroupPath string, pid int) error {
+	return applyCgroupV2(cgroupPath, pid)
+}
+
+func (m *memoryV2SubSystem) Remove(cgroupPath string) error {
+	return removeCgroupV2Path(cgroupPath)
+}
+
+func (m *memoryV2SubSystem) Stat(cgroupPath string, stats *Stats) error {
+	subsysCgroupPath, err := getCgroupV2Path(cgroupPath, false)
+	if err != nil {
+		return err
+	}
+	if stats.MemoryUsage, err = readUint(filepath.Join(subsysCgroupPath, "memory.current")); err != nil {
+		return fmt.Errorf("get cgroup memory usage fail %v", err)
+	}
+	if stats.MemoryLimit, err = readUint(filepath.Join(subsysCgroupPath, "memory.max")); err != nil {
+		return fmt.Errorf("get cgroup memory limit fail %v", err)
+	}
+	// memory.peak is only provided since linux 5.19
+	if peak, err := readUint(filepath.Join(subsysCgroupPath, "memory.peak")); err == nil {
+		stats.MemoryPeak = peak
+	}
+	events, err := readKeyValue(filepath.Join(subsysCgroupPath, "memory.events"))
+	if err != nil {
+		return fmt.Errorf("get cgroup memory events fail %v", err)
+	}
+	stats.OOMKills = events["oom_kill"]
+	return nil
+}
@@ -0,0 +1,198 @@
+package subsystems
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	// the default cfs period is 100ms
+	defaultCpuPeriod uint64 = 100000
+	// the kernel rejects quota less than 1ms
+	minCpuQuota uint64 = 1000
+)
+
+var (
+	bytesRegexp  = regexp.MustCompile(`^(\d+(\.\d+)?)\s*([kmgtp]?)(i?b)?$`)
+	cpusetRegexp = regexp.MustCompile(`^\d+(-\d+)?(,\d+(-\d+)?)*$`)
+	unitShift    = map[string]uint{"": 0, "k": 10, "m": 20, "g": 30, "t": 40, "p": 50}
+)
+
+// a throttle of a block device
+type deviceLimit struct {
+	major, minor uint64
+	rate         uint64
+}
+
+// ParseBytes parse a human-readable size in binary units to bytes,
+// e.g. 1024, 512m, 1.5g, 10MB, 2GiB
+func ParseBytes(size string) (uint64, error) {
+	match := bytesRegexp.FindStringSubmatch(strings.ToLower(strings.TrimSpace(size)))
+	if match == nil {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	bytes := value * float64(uint64(1)<<unitShift[match[3]])
+	// the conversion of the float out of range is undefined, the limits are int64 in the kernel
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q, it's too large", size)
+	}
+	return uint64(bytes), nil
+}
+
+// ParseCPUs parse the number of cpus(e.g. 0.5, 2) to the cfs quota and period
+func ParseCPUs(cpus string) (uint64, uint64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(cpus), 64)
+	if err != nil || value <= 0 {
+		return 0, 0, fmt.Errorf("invalid cpus %q, it must be a positive number", cpus)
+	}
+	if value > float64(runtime.NumCPU()) {
+		return 0, 0, fmt.Errorf("invalid cpus %q, only %d cpus are available", cpus, runtime.NumCPU())
+	}
+	quota := uint64(value * float64(defaultCpuPeriod))
+	if quota < minCpuQuota {
+		return 0, 0, fmt.Errorf("invalid cpus %q, the minimum is %.2f", cpus, float64(minCpuQuota)/float64(defaultCpuPeriod))
+	}
+	return quota, defaultCpuPeriod, nil
+}
+
+// ParsePidsLimit parse the max number of processes, 0 or -1 means unlimited
+func ParsePidsLimit(limit string) (int64, error) {
+	value, err := strconv.ParseInt(strings.TrimSpace(limit), 10, 64)
+	if err != nil || value < -1 {
+		return 0, fmt.Errorf("invalid pids limit %q", limit)
+	}
+	return value, nil
+}
+
+// parse the memory swap limit, which is the total of memory and swap.
+// -1 means unlimited swap
+func parseMemorySwap(swap string) (int64, error) {
+	if strings.TrimSpace(swap) == "-1" {
+		return -1, nil
+	}
+	value, err := ParseBytes(swap)
+	if err != nil {
+		return 0, err
+	}
+	return int64(value), nil
+}
+
+// parse the device throttle, the format is {device path}:{rate}, e.g. /dev/sda:1mb.
+// the rate is bytes per second if bytes is true, otherwise io per second
+func parseDeviceLimit(limit string, bytes bool) (*deviceLimit, error) {
+	path, rate, ok := strings.Cut(limit, ":")
+	if !ok || path == "" || rate == "" {
+		return nil, fmt.Errorf("invalid device limit %q, the format is <device-path>:<rate>", limit)
+	}
+	var value uint64
+	var err error
+	if bytes {
+		value, err = ParseBytes(rate)
+	} else {
+		value, err = strconv.ParseUint(rate, 10, 64)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid device limit %q, the rate %q is invalid", limit, rate)
+	}
+
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err != nil {
+		return nil, fmt.Errorf("invalid device limit %q, %v", limit, err)
+	}
+	if stat.Mode&syscall.S_IFMT != syscall.S_IFBLK {
+		return nil, fmt.Errorf("invalid device limit %q, %s isn't a block device", limit, path)
+	}
+	// reference: the major and minor macros of glibc
+	dev := uint64(stat.Rdev)
+	return &deviceLimit{
+		major: (dev>>8)&0xfff | (dev>>32)&^uint64(0xfff),
+		minor: dev&0xff | (dev>>12)&^uint64(0xff),
+		rate:  value,
+	}, nil
+}
+
+func parseDeviceLimits(limits []string, bytes bool) ([]*deviceLimit, error) {
+	devices := []*deviceLimit{}
+	for _, limit := range limits {
+		device, err := parseDeviceLimit(limit, bytes)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// Validate check all the limits before writing anything to the cgroup
+func (r *ResourceConfig) Validate() error {
+	var memory uint64
+	var err error
+	if r.MemoryLimit != "" {
+		if memory, err = ParseBytes(r.MemoryLimit); err != nil {
+			return fmt.Errorf("invalid memory limit: %v", err)
+		}
+		// the kernel rejects limits less than a few pages
+		if memory < 6<<20 {
+			return fmt.Errorf("invalid memory limit %q, the minimum is 6m", r.MemoryLimit)
+		}
+	}
+	if r.MemorySwap != "" {
+		if r.MemoryLimit == "" {
+			return fmt.Errorf("memory swap can only be set with memory limit")
+		}
+		swap, err := parseMemorySwap(r.MemorySwap)
+		if err != nil {
+			return fmt.Errorf("invalid memory swap: %v", err)
+		}
+		if swap != -1 && uint64(swap) < memory {
+			return fmt.Errorf("invalid memory swap %q, it should be larger than memory limit", r.MemorySwap)
+		}
+	}
+	if r.MemoryReservation != "" {
+		reservation, err := ParseBytes(r.MemoryReservation)
+		if err != nil {
+			return fmt.Errorf("invalid memory reservation: %v", err)
+		}
+		if r.MemoryLimit != "" && reservation > memory {
+			return fmt.Errorf("invalid memory reservation %q, it should be smaller than memory limit", r.MemoryReservation)
+		}
+	}
+	if r.CpuSet != "" && !cpusetRegexp.MatchString(r.CpuSet) {
+		return fmt.Errorf("invalid cpuset %q, the format is like 0-2,4", r.CpuSet)
+	}
+	if r.CpuShare != "" {
+		if shares, err := strconv.ParseUint(r.CpuShare, 10, 64); err != nil || shares < 2 {
+			return fmt.Errorf("invalid cpu share %q, it must be an integer not less than 2", r.CpuShare)
+		}
+	}
+	if r.Cpus != "" {
+		if _, _, err := ParseCPUs(r.Cpus); err != nil {
+			return err
+		}
+	}
+	if r.PidsLimit != "" {
+		if _, err := ParsePidsLimit(r.PidsLimit); err != nil {
+			return err
+		}
+	}
+	for _, limits := range [][]string{r.DeviceReadBps, r.DeviceWriteBps} {
+		if _, err := parseDeviceLimits(limits, true); err != nil {
+			return err
+		}
+	}
+	for _, limits := range [][]string{r.DeviceReadIOps, r.DeviceWriteIOps} {
+		if _, err := parseDeviceLimits(limits, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
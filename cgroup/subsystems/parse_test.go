@@ -0,0 +1,71 @@
+package subsystems
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBytes(t *testing.T) {
+	assert := assert.New(t)
+	cases := map[string]uint64{
+		"1024":  1024,
+		"512m":  512 << 20,
+		"1.5g":  3 << 29,
+		"10MB":  10 << 20,
+		"2GiB":  2 << 30,
+		"100 k": 100 << 10,
+		"8191p": 8191 << 50,
+	}
+	for size, expected := range cases {
+		value, err := ParseBytes(size)
+		assert.Nil(err, "parse %s should return nil", size)
+		assert.Equal(expected, value, "parse %s", size)
+	}
+	for _, size := range []string{"", "m", "-1", "1x", "1.5.5g", "8192p", "99999999999999999999p"} {
+		_, err := ParseBytes(size)
+		assert.NotNil(err, "parse %s should return error", size)
+	}
+}
+
+func TestParseCPUs(t *testing.T) {
+	assert := assert.New(t)
+	quota, period, err := ParseCPUs("0.5")
+	assert.Nil(err, "error is nil")
+	assert.Equal(uint64(50000), quota, "quota is half of period")
+	assert.Equal(uint64(100000), period, "default period")
+
+	for _, cpus := range []string{"0", "-1", "abc", "0.001", "100000"} {
+		_, _, err := ParseCPUs(cpus)
+		assert.NotNil(err, "parse %s should return error", cpus)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+	valid := []ResourceConfig{
+		{},
+		{MemoryLimit: "512m", MemorySwap: "1g", MemoryReservation: "256m"},
+		{MemoryLimit: "512m", MemorySwap: "-1"},
+		{CpuSet: "0-1,3", CpuShare: "512", PidsLimit: "100"},
+		{PidsLimit: "-1"},
+	}
+	for _, cfg := range valid {
+		assert.Nil(cfg.Validate(), "%+v is valid", cfg)
+	}
+	invalid := []ResourceConfig{
+		{MemoryLimit: "1k"},
+		{MemoryLimit: "abc"},
+		{MemorySwap: "1g"},
+		{MemoryLimit: "1g", MemorySwap: "512m"},
+		{MemoryLimit: "512m", MemoryReservation: "1g"},
+		{CpuSet: "0-"},
+		{CpuShare: "1"},
+		{PidsLimit: "-2"},
+		{DeviceReadBps: []string{"/dev/sda"}},
+		{DeviceWriteIOps: []string{"/dev/null:100"}},
+	}
+	for _, cfg := range invalid {
+		assert.NotNil(cfg.Validate(), "%+v is invalid", cfg)
+	}
+}
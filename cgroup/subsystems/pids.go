@@ -0,0 +1,62 @@
+package subsystems
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+type pidsSubSystem struct{}
+
+func (p *pidsSubSystem) Name() string {
+	return "pids"
+}
+
+func (p *pidsSubSystem) Set(cgroupPath string, cfg *ResourceConfig) error {
+	if subsysCgroupPath, err := getCgroupPath(p.Name(), cgroupPath, true); err != nil {
+		return err
+	} else {
+		if cfg.PidsLimit != "" {
+			if err := writeCgroupFile(subsysCgroupPath, "pids.max", formatPidsLimit(cfg.PidsLimit)); err != nil {
+				return fmt.Errorf("set cgroup pids fail %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *pidsSubSystem) Apply(cgroupPath string, pid int) error {
+	if subsysCgroupPath, err := getCgroupPath(p.Name(), cgroupPath, false); err != nil {
+		return err
+	} else {
+		if err = os.WriteFile(filepath.Join(subsysCgroupPath, "tasks"), []byte(fmt.Sprintf("%d", pid)), 0644); err != nil {
+			return fmt.Errorf("set cgroup proc fail %v", err)
+		}
+	}
+	return nil
+}
+
+func (p *pidsSubSystem) Remove(cgroupPath string) error {
+	return removeCgroupPath(p.Name(), cgroupPath)
+}
+
+func (p *pidsSubSystem) Stat(cgroupPath string, stats *Stats) error {
+	subsysCgroupPath, err := getCgroupPath(p.Name(), cgroupPath, false)
+	if err != nil {
+		return err
+	}
+	if stats.PidsCurrent, err = readUint(filepath.Join(subsysCgroupPath, "pids.current")); err != nil {
+		return fmt.Errorf("get cgroup pids current fail %v", err)
+	}
+	return nil
+}
+
+// 0 and -1 mean unlimited
+func formatPidsLimit(limit string) string {
+	value, err := ParsePidsLimit(limit)
+	if err != nil || value <= 0 {
+		return "max"
+	}
+	return strconv.FormatInt(value, 10)
+}
@@ -0,0 +1,50 @@
+package subsystems
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// pids controller of the unified hierarchy
+type pidsV2SubSystem struct{}
+
+func (p *pidsV2SubSystem) Name() string {
+	return "pids"
+}
+
+func (p *pidsV2SubSystem) Set(cgroupPath string, cfg *ResourceConfig) error {
+	if subsysCgroupPath, err := getCgroupV2Path(cgroupPath, true); err != nil {
+		return err
+	} else {
+		// stats reads pids.current, so the controller is also enabled without limits,
+		// but only the container with limits fails if it can't be enabled
+		if err := enableController(cgroupPath, p.Name()); err != nil && cfg.Requires(p.Name()) {
+			return err
+		}
+		if cfg.PidsLimit != "" {
+			if err := writeCgroupFile(subsysCgroupPath, "pids.max", formatPidsLimit(cfg.PidsLimit)); err != nil {
+				return fmt.Errorf("set cgroup pids fail %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *pidsV2SubSystem) Apply(cgroupPath string, pid int) error {
+	return applyCgroupV2(cgroupPath, pid)
+}
+
+func (p *pidsV2SubSystem) Remove(cgroupPath string) error {
+	return removeCgroupV2Path(cgroupPath)
+}
+
+func (p *pidsV2SubSystem) Stat(cgroupPath string, stats *Stats) error {
+	subsysCgroupPath, err := getCgroupV2Path(cgroupPath, false)
+	if err != nil {
+		return err
+	}
+	if stats.PidsCurrent, err = readUint(filepath.Join(subsysCgroupPath, "pids.current")); err != nil {
+		return fmt.Errorf("get cgroup pids current fail %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package subsystems
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// resource usage of a cgroup
+type Stats struct {
+	// total cpu time in nanoseconds
+	CpuUsage    uint64 `json:"cpu_usage"`
+	MemoryUsage uint64 `json:"memory_usage"`
+	MemoryPeak  uint64 `json:"memory_peak"`
+	// zero means unlimited
+	MemoryLimit uint64 `json:"memory_limit"`
+	PidsCurrent uint64 `json:"pids_current"`
+	BlockRead   uint64 `json:"block_read"`
+	BlockWrite  uint64 `json:"block_write"`
+	// the number of processes killed by the oom killer
+	OOMKills uint64 `json:"oom_kills"`
+}
+
+// read a file which only contains a number, "max" means unlimited(0)
+func readUint(path string) (uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(content))
+	if value == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// read a flat keyed file like cpu.stat and memory.events, "key value" per line
+func readKeyValue(path string) (map[string]uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseKeyValue(string(content))
+}
+
+func parseKeyValue(content string) (map[string]uint64, error) {
+	values := map[string]uint64{}
+	scan := bufio.NewScanner(strings.NewReader(content))
+	for scan.Scan() {
+		fields := strings.Fields(scan.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = v
+		}
+	}
+	return values, scan.Err()
+}
@@ -1,9 +1,19 @@
 package subsystems
 
+// the limits are human-readable strings, see Validate for the formats
 type ResourceConfig struct {
-	MemoryLimit string 
-	CpuSet string 
-	CpuShare string 
+	MemoryLimit       string `json:"memory_limit,omitempty"`
+	MemorySwap        string `json:"memory_swap,omitempty"`
+	MemoryReservation string `json:"memory_reservation,omitempty"`
+	CpuSet            string `json:"cpuset,omitempty"`
+	CpuShare          string `json:"cpushare,omitempty"`
+	Cpus              string `json:"cpus,omitempty"`
+	PidsLimit         string `json:"pids_limit,omitempty"`
+	// {device path}:{rate}
+	DeviceReadBps   []string `json:"device_read_bps,omitempty"`
+	DeviceWriteBps  []string `json:"device_write_bps,omitempty"`
+	DeviceReadIOps  []string `json:"device_read_iops,omitempty"`
+	DeviceWriteIOps []string `json:"device_write_iops,omitempty"`
 }
 
 
@@ -16,10 +26,93 @@ type SubSystem interface {
 	Apply(path string, pid int) error 
 	// remove cgroup
 	Remove(path string) error
+	// collect the resource usage of the cgroup
+	Stat(path string, stats *Stats) error
 }
 
-var SubSystems []SubSystem = []SubSystem{
-	&cpuSetSubSystem{},
-	&cpuSubSystem{},
-	&memorySubSystem{},
-}
\ No newline at end of file
+// the subsystem which can suspend all the processes of the cgroup
+type FreezerSubSystem interface {
+	SubSystem
+	// freeze or thaw the cgroup
+	Freeze(path string, frozen bool) error
+}
+
+// the subsystems are selected by the cgroup version of the host
+var SubSystems []SubSystem = newSubSystems()
+
+func newSubSystems() []SubSystem {
+	if IsCgroupV2() {
+		return []SubSystem{
+			&cpuSetV2SubSystem{},
+			&cpuV2SubSystem{},
+			&memoryV2SubSystem{},
+			&pidsV2SubSystem{},
+			&ioV2SubSystem{},
+			&freezerV2SubSystem{},
+		}
+	}
+	return []SubSystem{
+		&cpuSetSubSystem{},
+		&cpuSubSystem{},
+		&cpuAcctSubSystem{},
+		&memorySubSystem{},
+		&pidsSubSystem{},
+		&blkioSubSystem{},
+		&freezerSubSystem{},
+	}
+}
+
+// Merge override the limits with the non-empty limits of cfg
+func (r *ResourceConfig) Merge(cfg *ResourceConfig) {
+	if cfg.MemoryLimit != "" {
+		r.MemoryLimit = cfg.MemoryLimit
+	}
+	if cfg.CpuSet != "" {
+		r.CpuSet = cfg.CpuSet
+	}
+	if cfg.CpuShare != "" {
+		r.CpuShare = cfg.CpuShare
+	}
+	if cfg.MemorySwap != "" {
+		r.MemorySwap = cfg.MemorySwap
+	}
+	if cfg.MemoryReservation != "" {
+		r.MemoryReservation = cfg.MemoryReservation
+	}
+	if cfg.Cpus != "" {
+		r.Cpus = cfg.Cpus
+	}
+	if cfg.PidsLimit != "" {
+		r.PidsLimit = cfg.PidsLimit
+	}
+	if len(cfg.DeviceReadBps) != 0 {
+		r.DeviceReadBps = cfg.DeviceReadBps
+	}
+	if len(cfg.DeviceWriteBps) != 0 {
+		r.DeviceWriteBps = cfg.DeviceWriteBps
+	}
+	if len(cfg.DeviceReadIOps) != 0 {
+		r.DeviceReadIOps = cfg.DeviceReadIOps
+	}
+	if len(cfg.DeviceWriteIOps) != 0 {
+		r.DeviceWriteIOps = cfg.DeviceWriteIOps
+	}
+}
+
+// Requires check whether the limits of the subsystem are set, the subsystems
+// without limits are only used for the stats and pause
+func (r *ResourceConfig) Requires(subsystem string) bool {
+	switch subsystem {
+	case "memory":
+		return r.MemoryLimit != "" || r.MemorySwap != "" || r.MemoryReservation != ""
+	case "cpu":
+		return r.CpuShare != "" || r.Cpus != ""
+	case "cpuset":
+		return r.CpuSet != ""
+	case "pids":
+		return r.PidsLimit != ""
+	case "blkio", "io":
+		return len(r.DeviceReadBps)+len(r.DeviceWriteBps)+len(r.DeviceReadIOps)+len(r.DeviceWriteIOps) > 0
+	}
+	return false
+}
@@ -8,32 +8,69 @@ import (
 	"strings"
 )
 
-func findCgroupMountPoint(subsystem string) string {
+// a line of /proc/self/mountinfo
+type mountInfo struct {
+	mountPoint string
+	fsType     string
+	options    []string
+}
+
+// parse /proc/self/mountinfo, the format is:
+// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+func parseMountInfo() []mountInfo {
 	f, err := os.Open("/proc/self/mountinfo")
 	if err != nil {
-		return ""
+		return nil
 	}
 	defer f.Close()
 
+	mounts := []mountInfo{}
 	scan := bufio.NewScanner(f)
-	prefix := filepath.Join("/cgroup", subsystem)
 	for scan.Scan() {
-		txt := scan.Text()
-		if !strings.Contains(txt, prefix) {continue}
-		return strings.Split(txt, " ")[4]
+		// the optional fields end with a single hyphen
+		fields := strings.SplitN(scan.Text(), " - ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		pre, post := strings.Fields(fields[0]), strings.Fields(fields[1])
+		if len(pre) < 5 || len(post) < 3 {
+			continue
+		}
+		mounts = append(mounts, mountInfo{
+			mountPoint: pre[4],
+			fsType:     post[0],
+			options:    strings.Split(post[2], ","),
+		})
 	}
-	if err := scan.Err(); err != nil {
-		return ""
+	return mounts
+}
+
+// find the mount point of the cgroup v1 hierarchy which the subsystem attached
+func findCgroupMountPoint(subsystem string) string {
+	for _, mount := range parseMountInfo() {
+		if mount.fsType != "cgroup" {
+			continue
+		}
+		// the super options contain the attached subsystems, e.g. rw,cpu,cpuacct
+		for _, opt := range mount.options {
+			if opt == subsystem {
+				return mount.mountPoint
+			}
+		}
 	}
 	return ""
 }
 
 func getCgroupPath(subsystem string, cgroupPath string, autoCreate bool) (string, error) {
 	cgroupRoot := findCgroupMountPoint(subsystem)
+	if cgroupRoot == "" {
+		return "", fmt.Errorf("cgroup subsystem %s isn't mounted", subsystem)
+	}
 	cgroupPath = filepath.Join(cgroupRoot, cgroupPath)
 	if _, err := os.Stat(cgroupPath); err == nil || autoCreate && os.IsNotExist(err) {
 		if os.IsNotExist(err) {
-			err = os.Mkdir(cgroupPath, 0755)
+			// the container cgroup is nested in the mini-docker cgroup
+			err = os.MkdirAll(cgroupPath, 0755)
 			if err != nil {
 				return "", fmt.Errorf("create cgroup error %v", err)
 			}
@@ -42,4 +79,57 @@ func getCgroupPath(subsystem string, cgroupPath string, autoCreate bool) (string
 	} else {
 		return "", fmt.Errorf("cgroup path error %v", err)
 	}
-}
\ No newline at end of file
+}
+
+// remove the cgroup directory, a missing cgroup isn't an error
+func removeCgroupPath(subsystem string, cgroupPath string) error {
+	cgroupRoot := findCgroupMountPoint(subsystem)
+	if cgroupRoot == "" {
+		return nil
+	}
+	cgroupPath = filepath.Join(cgroupRoot, cgroupPath)
+	// cgroup directory can only be removed by rmdir
+	if err := os.Remove(cgroupPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove cgroup %s error %v", cgroupPath, err)
+	}
+	return nil
+}
+
+// write the value to the file of the cgroup
+func writeCgroupFile(cgroupPath, file, value string) error {
+	if err := os.WriteFile(filepath.Join(cgroupPath, file), []byte(value), 0644); err != nil {
+		return fmt.Errorf("write %s error %v", file, err)
+	}
+	return nil
+}
+
+// ListChildren list the names of the child cgroups in all the mounted
+// hierarchies, the cgroup may only exist in some of them
+func ListChildren(cgroupPath string) []string {
+	roots := []string{}
+	if IsCgroupV2() {
+		roots = append(roots, findCgroupV2MountPoint())
+	} else {
+		for _, sub := range SubSystems {
+			roots = append(roots, findCgroupMountPoint(sub.Name()))
+		}
+	}
+	seen := map[string]bool{}
+	children := []string{}
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(root, cgroupPath))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() && !seen[entry.Name()] {
+				seen[entry.Name()] = true
+				children = append(children, entry.Name())
+			}
+		}
+	}
+	return children
+}
@@ -0,0 +1,108 @@
+package subsystems
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsCgroupV2 report whether the host only uses the unified hierarchy.
+// the hybrid mode(v1 controllers with an empty cgroup2 mount) is treated as v1
+func IsCgroupV2() bool {
+	unified := false
+	for _, mount := range parseMountInfo() {
+		switch mount.fsType {
+		case "cgroup":
+			return false
+		case "cgroup2":
+			unified = true
+		}
+	}
+	return unified
+}
+
+// find the mount point of the unified hierarchy
+func findCgroupV2MountPoint() string {
+	for _, mount := range parseMountInfo() {
+		if mount.fsType == "cgroup2" {
+			return mount.mountPoint
+		}
+	}
+	return ""
+}
+
+func getCgroupV2Path(cgroupPath string, autoCreate bool) (string, error) {
+	cgroupRoot := findCgroupV2MountPoint()
+	if cgroupRoot == "" {
+		return "", fmt.Errorf("cgroup2 isn't mounted")
+	}
+	cgroupPath = filepath.Join(cgroupRoot, cgroupPath)
+	if _, err := os.Stat(cgroupPath); err == nil || autoCreate && os.IsNotExist(err) {
+		if os.IsNotExist(err) {
+			if err := os.MkdirAll(cgroupPath, 0755); err != nil {
+				return "", fmt.Errorf("create cgroup error %v", err)
+			}
+		}
+		return cgroupPath, nil
+	} else {
+		return "", fmt.Errorf("cgroup path error %v", err)
+	}
+}
+
+// enable the controller for the cgroup, in the unified hierarchy a controller
+// is only available when all the ancestors enable it in cgroup.subtree_control
+func enableController(cgroupPath, controller string) error {
+	parent := findCgroupV2MountPoint()
+	if parent == "" {
+		return fmt.Errorf("cgroup2 isn't mounted")
+	}
+	for _, dir := range strings.Split(filepath.Clean(cgroupPath), string(filepath.Separator)) {
+		subtreeControl := filepath.Join(parent, "cgroup.subtree_control")
+		enabled, err := os.ReadFile(subtreeControl)
+		if err != nil {
+			return err
+		}
+		if !containsController(string(enabled), controller) {
+			if err := os.WriteFile(subtreeControl, []byte("+"+controller), 0644); err != nil {
+				return fmt.Errorf("enable controller %s in %s error %v", controller, parent, err)
+			}
+		}
+		parent = filepath.Join(parent, dir)
+	}
+	return nil
+}
+
+func containsController(controllers, controller string) bool {
+	for _, c := range strings.Fields(controllers) {
+		if c == controller {
+			return true
+		}
+	}
+	return false
+}
+
+// the processes of all the controllers are in the same cgroup.procs
+func applyCgroupV2(cgroupPath string, pid int) error {
+	subsysCgroupPath, err := getCgroupV2Path(cgroupPath, false)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(subsysCgroupPath, "cgroup.procs"), []byte(fmt.Sprintf("%d", pid)), 0644); err != nil {
+		return fmt.Errorf("set cgroup proc fail %v", err)
+	}
+	return nil
+}
+
+// all the controllers share one directory, so the first Remove does the job
+func removeCgroupV2Path(cgroupPath string) error {
+	cgroupRoot := findCgroupV2MountPoint()
+	if cgroupRoot == "" {
+		return nil
+	}
+	cgroupPath = filepath.Join(cgroupRoot, cgroupPath)
+	if err := os.Remove(cgroupPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove cgroup %s error %v", cgroupPath, err)
+	}
+	return nil
+}
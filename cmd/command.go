@@ -5,12 +5,13 @@ import (
 	"mini-docker/cgroup/subsystems"
 	netcmd "mini-docker/cmd/network"
 	"mini-docker/container"
-	"mini-docker/network"
+	"mini-docker/label"
+	"mini-docker/logdriver"
 	"mini-docker/runtime"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
-	"go.uber.org/zap"
 )
 
 var (
@@ -18,27 +19,83 @@ var (
 		Use:   "run imageName containerCommand",
 		Short: "run command creates container with Namespace and Cgroup",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// check --ti and -d
-			if ti && daemon {
-				return fmt.Errorf("ti and d paramter can't both provided")
+			// --ti is the same as -i -t
+			interactive, tty = interactive || ti, tty || ti
+			if _, err := container.ParseDetachKeys(detachKeys); err != nil {
+				return err
 			}
-			cfg := &subsystems.ResourceConfig{
-				MemoryLimit: m,
-				CpuSet:      cpuset,
-				CpuShare:    cpushare,
+			logOpts, err := container.ParseLogOptions(logOpt)
+			if err != nil {
+				return err
 			}
-			imageName, command := args[0], args[1:]
-			runtime.Run(ti, command, env, volume, port, cfg, imageName, name, net)
-			return nil
+			if err := logdriver.Validate(logDriver, logOpts); err != nil {
+				return err
+			}
+			cfg := &resource
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+			policy, err := container.ParseRestartPolicy(restart)
+			if err != nil {
+				return err
+			}
+			if _, err := container.ParseSignal(stopSignal); err != nil {
+				return err
+			}
+			labels, err := label.Parse(labelList, labelFiles)
+			if err != nil {
+				return err
+			}
+			// the foreground container is removed after it exits
+			if !policy.IsNone() && !daemon {
+				return fmt.Errorf("restart policy can only be used with detached container")
+			}
+			opts := &runtime.RunOptions{
+				TTY:           tty,
+				Interactive:   interactive,
+				Detach:        daemon,
+				Args:          args[1:],
+				Env:           env,
+				Volumes:       volume,
+				Ports:         port,
+				Resource:      cfg,
+				Image:         args[0],
+				Name:          name,
+				Network:       net,
+				RestartPolicy: policy,
+				StopSignal:    stopSignal,
+				Init:          initProcess,
+				Workdir:       workdir,
+				User:          user,
+				Hostname:      hostname,
+				DetachKeys:    detachKeys,
+				LogDriver:     logDriver,
+				LogOpts:       logOpts,
+				Labels:        labels,
+			}
+			return exitWithCode(runtime.Run(opts))
 		},
 		Args: cobra.MinimumNArgs(1),
 	}
 
+	shimCmd = &cobra.Command{
+		Use:    "shim",
+		Short:  "shim supervises the detached container, don't call outside",
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runtime.Shim()
+		},
+	}
+
 	commitCmd = &cobra.Command{
 		Use:   "commit containerName newImageName",
 		Short: "commit container into image",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			runtime.CommitContainer(args[0], args[1])
+			containerName, err := resolveContainer(args[0])
+			if err != nil {
+				return err
+			}
+			runtime.CommitContainer(containerName, args[1])
 			return nil
 		},
 		Args: cobra.MinimumNArgs(2),
@@ -56,16 +113,41 @@ var (
 	psCmd = &cobra.Command{
 		Use:   "ps",
 		Short: "list the container",
-		Run: func(cmd *cobra.Command, args []string) {
-			container.ListContainer()
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return container.ListContainer(&psOpts)
 		},
 	}
 
 	logCmd = &cobra.Command{
-		Use:   "log containerName",
-		Short: "print logs of the container",
-		Run: func(cmd *cobra.Command, args []string) {
-			container.GetContainerLog(args[0])
+		Use:     "log containerName",
+		Aliases: []string{"logs"},
+		Short:   "print logs of the container",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tail, err := container.ParseLogTail(logTail)
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			since, err := container.ParseLogTime(logSince, now)
+			if err != nil {
+				return err
+			}
+			until, err := container.ParseLogTime(logUntil, now)
+			if err != nil {
+				return err
+			}
+			cfg := &container.LogsConfig{
+				Follow:     logFollow,
+				Tail:       tail,
+				Since:      since,
+				Until:      until,
+				Timestamps: logTimestamps,
+			}
+			containerName, err := resolveContainer(args[0])
+			if err != nil {
+				return err
+			}
+			return container.GetContainerLog(containerName, cfg)
 		},
 		Args: cobra.MinimumNArgs(1),
 	}
@@ -74,15 +156,41 @@ var (
 		Use:   "exec containerName command",
 		Short: "exec a command into container",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// the exec process in the namespaces of the container
 			if os.Getenv(container.ENV_EXEC_PID) != "" {
-				zap.L().Sugar().Infof("pid call back pid %d", os.Getpid())
-				return nil
+				return container.ExecInit()
 			}
 			// check args length
 			if len(args) < 2 {
 				return fmt.Errorf("missing container name or command")
 			}
-			container.ExecContainer(args[0], args[1:])
+			if daemon && (tty || interactive) {
+				return fmt.Errorf("the detached command can't be attached, -d can't be used with -i or -t")
+			}
+			opts := &container.ExecOptions{
+				Args:        args[1:],
+				Env:         env,
+				Workdir:     workdir,
+				User:        user,
+				TTY:         tty,
+				Interactive: interactive,
+				Detach:      daemon,
+			}
+			containerName, err := resolveContainer(args[0])
+			if err != nil {
+				return err
+			}
+			exitCode, err := container.ExecContainer(containerName, opts)
+			if err != nil && exitCode <= 0 {
+				return err
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+			// the exit code of the command is the exit code of exec
+			if exitCode != 0 {
+				os.Exit(exitCode)
+			}
 			return nil
 		},
 	}
@@ -91,8 +199,68 @@ var (
 		Use:   "stop containerName",
 		Short: "stop the container",
 		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			container.StopContainer(args[0])
+		RunE: func(cmd *cobra.Command, args []string) error {
+			containerName, err := resolveContainer(args[0])
+			if err != nil {
+				return err
+			}
+			return container.StopContainer(containerName, time.Duration(stopTime)*time.Second)
+		},
+	}
+
+	killCmd = &cobra.Command{
+		Use:   "kill containerName",
+		Short: "send a signal to the container",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sig, err := container.ParseSignal(signal)
+			if err != nil {
+				return err
+			}
+			containerName, err := resolveContainer(args[0])
+			if err != nil {
+				return err
+			}
+			return container.KillContainer(containerName, sig)
+		},
+	}
+
+	startCmd = &cobra.Command{
+		Use:   "start containerName",
+		Short: "start the stopped container",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			containerName, err := resolveContainer(args[0])
+			if err != nil {
+				return err
+			}
+			return exitWithCode(runtime.StartContainer(containerName, attach, detachKeys))
+		},
+	}
+
+	attachCmd = &cobra.Command{
+		Use:   "attach containerName",
+		Short: "attach the terminal to the running container",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			containerName, err := resolveContainer(args[0])
+			if err != nil {
+				return err
+			}
+			return exitWithCode(runtime.AttachContainer(containerName, detachKeys))
+		},
+	}
+
+	restartCmd = &cobra.Command{
+		Use:   "restart containerName",
+		Short: "restart the container",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			containerName, err := resolveContainer(args[0])
+			if err != nil {
+				return err
+			}
+			return runtime.RestartContainer(containerName, time.Duration(stopTime)*time.Second)
 		},
 	}
 
@@ -100,9 +268,94 @@ var (
 		Use:   "rm containerName",
 		Short: "remove the container",
 		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			network.DisConnect(args[0])
-			container.RemoveContainer(args[0])
+		RunE: func(cmd *cobra.Command, args []string) error {
+			containerName, err := resolveContainer(args[0])
+			if err != nil {
+				return err
+			}
+			return runtime.RemoveContainer(containerName)
+		},
+	}
+
+	statsCmd = &cobra.Command{
+		Use:   "stats [containerName...]",
+		Short: "display the resource usage of the containers",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			containerNames := []string{}
+			for _, ref := range args {
+				containerName, err := resolveContainer(ref)
+				if err != nil {
+					return err
+				}
+				containerNames = append(containerNames, containerName)
+			}
+			return container.ContainerStatistics(containerNames, noStream, statsFormat)
+		},
+	}
+
+	updateCmd = &cobra.Command{
+		Use:   "update containerName...",
+		Short: "update the resource limits of the containers",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().NFlag() == 0 {
+				return fmt.Errorf("you must provide at least one resource limit")
+			}
+			for _, ref := range args {
+				containerName, err := resolveContainer(ref)
+				if err != nil {
+					return err
+				}
+				if err := container.UpdateContainer(containerName, &resource); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	pauseCmd = &cobra.Command{
+		Use:   "pause containerName...",
+		Short: "pause all processes within the containers",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, ref := range args {
+				containerName, err := resolveContainer(ref)
+				if err != nil {
+					return err
+				}
+				if err := container.PauseContainer(containerName); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	unpauseCmd = &cobra.Command{
+		Use:   "unpause containerName...",
+		Short: "unpause all processes within the containers",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, ref := range args {
+				containerName, err := resolveContainer(ref)
+				if err != nil {
+					return err
+				}
+				if err := container.UnpauseContainer(containerName); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	inspectCmd = &cobra.Command{
+		Use:   "inspect name...",
+		Short: "display the detailed information of containers, networks or images",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runtime.Inspect(args, inspectType, inspectFormat)
 		},
 	}
 
@@ -111,36 +364,176 @@ var (
 		Short: "container network commands",
 		Run: func(cmd *cobra.Command, args []string) {},
 	}
+
+	systemCmd = &cobra.Command{
+		Use:   "system",
+		Short: "manage mini-docker",
+		Run:   func(cmd *cobra.Command, args []string) {},
+	}
+
+	systemPruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "remove the stopped containers and the networks not used by any container",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runtime.SystemPrune(pruneFilters)
+		},
+	}
+
+	systemCheckCmd = &cobra.Command{
+		Use:   "check",
+		Short: "find the stale containers and the leaked resources, e.g. after the host reboots",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runtime.CheckSystem(checkFix)
+		},
+	}
 )
 
 var (
 	// generic
-	ti     bool
-	volume []string
-	daemon bool
-	name   string
-	env    []string
+	ti          bool
+	tty         bool
+	interactive bool
+	volume      []string
+	daemon      bool
+	name        string
+	env         []string
+	// restart policy
+	restart string
+	// run an init process as pid 1
+	initProcess bool
+	// process
+	workdir  string
+	user     string
+	hostname string
+	// stop
+	stopSignal string
+	stopTime   int
+	signal     string
 	// network
 	net  string
 	port []string
 	// cgroup
-	m        string
-	cpuset   string
-	cpushare string
+	resource subsystems.ResourceConfig
+	// stats
+	noStream    bool
+	statsFormat string
+	// start
+	attach bool
+	// attach
+	detachKeys string
+	// log
+	logDriver     string
+	logOpt        []string
+	logFollow     bool
+	logTail       string
+	logSince      string
+	logUntil      string
+	logTimestamps bool
+	// ps
+	psOpts container.PsOptions
+	// inspect
+	inspectType   string
+	inspectFormat string
+	// label
+	labelList  []string
+	labelFiles []string
+	// system check
+	checkFix bool
+	// system prune
+	pruneFilters []string
 )
 
 func init() {
 	// flag
-	runCmd.Flags().BoolVar(&ti, "ti", false, "enable tty")
-	runCmd.Flags().StringVar(&m, "m", "", "set memory limit")
-	runCmd.Flags().StringVar(&cpuset, "cpuset", "", "set the cgroup process can be used in the CPU and memory")
-	runCmd.Flags().StringVar(&cpushare, "cpushare", "", "set the cpu schedule for the processes in cgroup")
+	runCmd.Flags().BoolVar(&ti, "ti", false, "enable tty, the same as -i -t")
+	runCmd.Flags().BoolVarP(&tty, "tty", "t", false, "allocate a pseudo-TTY")
+	runCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "keep STDIN open")
+	execCmd.Flags().BoolVarP(&tty, "tty", "t", false, "allocate a pseudo-TTY")
+	execCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "keep STDIN open")
+	execCmd.Flags().BoolVarP(&daemon, "detach", "d", false, "run the command in the background")
+	execCmd.Flags().StringArrayVarP(&env, "env", "e", []string{}, "set environment")
+	execCmd.Flags().StringVarP(&workdir, "workdir", "w", "", "working directory inside the container")
+	execCmd.Flags().StringVarP(&user, "user", "u", "", "username or uid, the format is <name|uid>[:<group|gid>]")
+	addResourceFlags(runCmd)
+	runCmd.Flags().StringArrayVar(&resource.DeviceReadBps, "device-read-bps", []string{}, "limit read rate (bytes per second) from a device, e.g. /dev/sda:1mb")
+	runCmd.Flags().StringArrayVar(&resource.DeviceWriteBps, "device-write-bps", []string{}, "limit write rate (bytes per second) to a device, e.g. /dev/sda:1mb")
+	runCmd.Flags().StringArrayVar(&resource.DeviceReadIOps, "device-read-iops", []string{}, "limit read rate (IO per second) from a device, e.g. /dev/sda:1000")
+	runCmd.Flags().StringArrayVar(&resource.DeviceWriteIOps, "device-write-iops", []string{}, "limit write rate (IO per second) to a device, e.g. /dev/sda:1000")
 	runCmd.Flags().StringArrayVarP(&volume, "volume", "v", []string{}, "set the volume of the container")
 	runCmd.Flags().BoolVarP(&daemon, "detach", "d", false, "detach container")
 	runCmd.Flags().StringVar(&name, "name", "", "set the container name")
 	runCmd.Flags().StringArrayVarP(&env, "env", "e", []string{}, "set environment")
 	runCmd.Flags().StringArrayVarP(&port, "port", "p", []string{}, "port mapping")
 	runCmd.Flags().StringVar(&net, "net", "", "set the container network")
+	runCmd.Flags().StringVarP(&workdir, "workdir", "w", "", "working directory inside the container")
+	runCmd.Flags().StringVarP(&user, "user", "u", "", "username or uid, the format is <name|uid>[:<group|gid>]")
+	runCmd.Flags().StringVar(&hostname, "hostname", "", "container host name")
+	runCmd.Flags().StringVar(&logDriver, "log-driver", container.JSONFileLogDriver, "log driver, json-file, none, syslog or fluentd")
+	runCmd.Flags().StringArrayVar(&logOpt, "log-opt", []string{}, "log driver options, e.g. max-size=10m, syslog-address=udp://127.0.0.1:514")
+	// the flags after the image belong to the container command
+	runCmd.Flags().SetInterspersed(false)
+	execCmd.Flags().SetInterspersed(false)
+	runCmd.Flags().BoolVar(&initProcess, "init", false, "run an init inside the container that forwards signals and reaps processes")
+	runCmd.Flags().StringVar(&stopSignal, "stop-signal", container.DefaultStopSignal, "signal to stop the container")
+	runCmd.Flags().StringVar(&restart, "restart", container.RestartNo, "restart policy, no, on-failure[:max-retries], always or unless-stopped")
+	statsCmd.Flags().BoolVar(&noStream, "no-stream", false, "print the first result only")
+	statsCmd.Flags().StringVar(&statsFormat, "format", "table", "output format, table or json")
+	addResourceFlags(updateCmd)
+	stopCmd.Flags().IntVarP(&stopTime, "time", "t", 10, "seconds to wait before killing the container")
+	restartCmd.Flags().IntVarP(&stopTime, "time", "t", 10, "seconds to wait before killing the container")
+	killCmd.Flags().StringVarP(&signal, "signal", "s", "KILL", "signal to send to the container")
+	startCmd.Flags().BoolVarP(&attach, "attach", "a", false, "attach the tty of the container")
+	logCmd.Flags().BoolVarP(&logFollow, "follow", "f", false, "follow the log output until the container exits")
+	logCmd.Flags().StringVarP(&logTail, "tail", "n", "all", "number of lines to show from the end of the log")
+	logCmd.Flags().StringVar(&logSince, "since", "", "show the log since the timestamp(e.g. 2024-01-02T15:04:05Z) or the relative time(e.g. 10m)")
+	logCmd.Flags().StringVar(&logUntil, "until", "", "show the log before the timestamp(e.g. 2024-01-02T15:04:05Z) or the relative time(e.g. 10m)")
+	logCmd.Flags().BoolVarP(&logTimestamps, "timestamps", "t", false, "show timestamps")
+	psCmd.Flags().BoolVarP(&psOpts.All, "all", "a", false, "show all containers, only the running containers are shown by default")
+	psCmd.Flags().BoolVarP(&psOpts.Quiet, "quiet", "q", false, "only display container ids")
+	psCmd.Flags().StringArrayVarP(&psOpts.Filters, "filter", "f", []string{}, "filter the containers, e.g. status=exited, name=web, label=team=infra, ancestor=busybox, network=net0")
+	psCmd.Flags().StringVar(&psOpts.Format, "format", "table", "output format, table, json, or the go template, e.g. 'table {{.ID}}\\t{{.Name}}'")
+	inspectCmd.Flags().StringVar(&inspectType, "type", "", "the type of the object, container, network or image")
+	inspectCmd.Flags().StringVarP(&inspectFormat, "format", "f", "", "format the output by the go template, e.g. '{{.IP}}'")
+	for _, c := range []*cobra.Command{runCmd, startCmd, attachCmd} {
+		c.Flags().StringVar(&detachKeys, "detach-keys", container.DefaultDetachKeys, "key sequence for detaching from the container")
+	}
+	runCmd.Flags().StringArrayVarP(&labelList, "label", "l", []string{}, "set the metadata of the container, e.g. team=infra")
+	runCmd.Flags().StringArrayVar(&labelFiles, "label-file", []string{}, "read the labels from the file, a label per line")
+	systemCheckCmd.Flags().BoolVar(&checkFix, "fix", false, "repair or remove the stale state")
+	systemPruneCmd.Flags().StringArrayVar(&pruneFilters, "filter", []string{}, "only remove the objects matching the filter, e.g. label=team=infra")
 	// child command
-	networkCmd.AddCommand(netcmd.CreateCmd, netcmd.ListCmd, netcmd.RemoveCmd)
+	networkCmd.AddCommand(netcmd.CreateCmd, netcmd.ListCmd, netcmd.RemoveCmd, netcmd.PruneCmd)
+	systemCmd.AddCommand(systemCheckCmd, systemPruneCmd)
+}
+
+// the resource limit flags shared by run and update
+func addResourceFlags(c *cobra.Command) {
+	c.Flags().StringVar(&resource.MemoryLimit, "m", "", "set memory limit, e.g. 512m, 1.5g")
+	c.Flags().StringVar(&resource.MemorySwap, "memory-swap", "", "set the total limit of memory and swap, -1 means unlimited swap")
+	c.Flags().StringVar(&resource.MemoryReservation, "memory-reservation", "", "set memory soft limit")
+	c.Flags().StringVar(&resource.CpuSet, "cpuset", "", "set the cgroup process can be used in the CPU and memory")
+	c.Flags().StringVar(&resource.CpuShare, "cpushare", "", "set the cpu schedule for the processes in cgroup")
+	c.Flags().StringVar(&resource.Cpus, "cpus", "", "set the number of cpus, e.g. 0.5")
+	c.Flags().StringVar(&resource.PidsLimit, "pids-limit", "", "set the max number of processes, -1 means unlimited")
+}
+
+// the exit code of the attached container is the exit code of the command
+func exitWithCode(exitCode int, err error) error {
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+	return nil
+}
+
+// resolve the container by the id, the id prefix or the name, return the name
+// which is the directory of the container
+func resolveContainer(ref string) (string, error) {
+	meta, err := container.GetContainer(ref)
+	if err != nil {
+		return "", err
+	}
+	return meta.Name, nil
 }
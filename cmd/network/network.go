@@ -2,6 +2,7 @@ package network
 
 import (
 	"fmt"
+	"mini-docker/label"
 	"mini-docker/network"
 
 	"github.com/spf13/cobra"
@@ -13,8 +14,12 @@ var (
 		Short: "create container network",
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			labels, err := label.Parse(labelList, labelFiles)
+			if err != nil {
+				return err
+			}
 			network.Init()
-			if err := network.CreateNetwork(args[0], subnet, driver); err != nil {
+			if err := network.CreateNetwork(args[0], subnet, driver, labels); err != nil {
 				return fmt.Errorf("create network error %v", err)
 			}
 			return nil
@@ -43,8 +48,25 @@ var (
 			if err := network.Init(); err != nil {
 				return fmt.Errorf("net work init error %v", err)
 			}
-			network.ListNetwork()
-			return nil
+			return network.ListNetwork(filters)
+		},
+	}
+
+	PruneCmd = &cobra.Command{
+		Use: "prune",
+		Short: "remove the networks not used by any container",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := network.Init(); err != nil {
+				return fmt.Errorf("net work init error %v", err)
+			}
+			removed, err := network.PruneNetworks(filters)
+			if len(removed) > 0 {
+				fmt.Println("Deleted Networks:")
+				for _, name := range removed {
+					fmt.Println(name)
+				}
+			}
+			return err
 		},
 	}
 )
@@ -52,9 +74,16 @@ var (
 var (
 	subnet string 
 	driver string
+	labelList  []string
+	labelFiles []string
+	filters    []string
 )
 
 func init() {
 	CreateCmd.Flags().StringVar(&subnet, "subnet", "", "subnet cidr")
 	CreateCmd.Flags().StringVar(&driver, "driver", "", "network driver")
+	CreateCmd.Flags().StringArrayVar(&labelList, "label", []string{}, "set the metadata of the network, e.g. team=infra")
+	CreateCmd.Flags().StringArrayVar(&labelFiles, "label-file", []string{}, "read the labels from the file, a label per line")
+	ListCmd.Flags().StringArrayVarP(&filters, "filter", "f", []string{}, "filter the networks, e.g. name=net0, driver=bridge, label=team=infra")
+	PruneCmd.Flags().StringArrayVar(&filters, "filter", []string{}, "only remove the networks matching the filter, e.g. label=team=infra")
 }
\ No newline at end of file
@@ -1,19 +1,37 @@
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"mini-docker/container"
+	"mini-docker/runtime"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
 
 var rootCmd = &cobra.Command{
 	Use: "mini-docker",
 	Short: "mini-docker is a simple container implementation.",
 	Run: func(cmd *cobra.Command, args []string) {},
+	// the state of the last boot is reconciled before the first command
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// the processes started by mini-docker itself, system check reconciles by itself
+		if cmd == initCmd || cmd == shimCmd || cmd == systemCheckCmd || os.Getenv(container.ENV_EXEC_PID) != "" {
+			return
+		}
+		if err := runtime.ReconcileOnBoot(); err != nil {
+			zap.L().Sugar().Warnf("reconcile the state error %v", err)
+		}
+	},
 }
 
 func Execute() error {return rootCmd.Execute()}
 
 func init() {
 	rootCmd.AddCommand(
-		initCmd, runCmd, commitCmd, psCmd, 
-		logCmd, execCmd, stopCmd, removeCmd,
-		networkCmd,
+		initCmd, shimCmd, runCmd, commitCmd, psCmd, 
+		logCmd, execCmd, stopCmd, startCmd, restartCmd, attachCmd, killCmd, removeCmd,
+		networkCmd, statsCmd, updateCmd,
+		pauseCmd, unpauseCmd, inspectCmd, systemCmd,
 	)
 }
\ No newline at end of file
@@ -17,10 +17,10 @@ var (
 func init() {
 	home := os.Getenv("HOME")
 	ImagePath, ContainerPath = filepath.Join(home, ImagePath), filepath.Join(home, ContainerPath)
-	if err := os.MkdirAll(ImagePath, 0622); err != nil {
+	if err := os.MkdirAll(ImagePath, 0755); err != nil {
 		zap.L().Sugar().Errorf("mkdir %s error %v", ImagePath, err)
 	}
-	if err := os.MkdirAll(ContainerPath, 0622); err != nil {
+	if err := os.MkdirAll(ContainerPath, 0755); err != nil {
 		zap.L().Sugar().Errorf("mkdir %s error %v", ContainerPath, err)
 	}
 }
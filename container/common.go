@@ -1,214 +1,266 @@
 package container
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io/fs"
+	"mini-docker/cgroup"
+	"mini-docker/cgroup/subsystems"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"syscall"
-	"text/tabwriter"
 	"time"
 
 	"go.uber.org/zap"
 )
 
-func ExecContainer(containerName string, args []string) {
-	pid, err := getPidByContainerName(containerName)
-	if err != nil {
-		zap.L().Sugar().Error("get pid error %v", err)
-		return
-	}
-	command := strings.Join(args, " ")
-	zap.L().Sugar().Infof("container pid %d", pid)
-	zap.L().Sugar().Infof("command %s", command)
-
-	cmd := exec.Command(prgPath, "exec")
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	env, err := getPidEnv(pid)
-	if err != nil {
-		zap.L().Sugar().Errorf("get pid environment error %v", err)
-		return
-	}
-	// set environment
-	os.Setenv(ENV_EXEC_PID, fmt.Sprint(pid))
-	os.Setenv(ENV_EXEC_CMD, command)
-	cmd.Env = append(os.Environ(), env...)
-	if err := cmd.Run(); err != nil {
-		zap.L().Sugar().Errorf("exec container %s error %s", containerName, err)
-	}
-}
-
-func getPidEnv(pid int) ([]string, error) {
-	pidPath := fmt.Sprintf("/proc/%d/environ", pid)
-	f, err := os.ReadFile(pidPath)
-	if err != nil {
-		zap.L().Sugar().Errorf("read the file %s error %v", pidPath, err)
-		return nil, err
-	}
-	env := strings.Split(string(f), "\u0000")
-	return env, nil
-}
-
-func getPidByContainerName(containerName string) (int, error) {
-	dirPath := fmt.Sprintf(DefaultInfoPath, containerName)
-	cfgPath := filepath.Join(dirPath, ConfigName)
-	cfg, err := os.ReadFile(cfgPath)
-	if err != nil {
-		zap.L().Sugar().Errorf("read the config file error %v", err)
-		return -1, err
-	}
-	meta := new(ContainerMeta)
-	if err := json.Unmarshal(cfg, meta); err != nil {
-		zap.L().Sugar().Errorf("unmarshal config file error %v", err)
-		return -1, err
-	}
-	return meta.PID, nil
-}
-
 func GetContainerByName(containerName string) (*ContainerMeta, error) {
-	dirPath := fmt.Sprintf(DefaultInfoPath, containerName)
-	cfgPath := filepath.Join(dirPath, ConfigName)
-	cfg, err := os.ReadFile(cfgPath)
-	if err != nil {
-		zap.L().Sugar().Errorf("read the config file error %v", err)
-		return nil, err
-	}
 	meta := new(ContainerMeta)
-	if err := json.Unmarshal(cfg, meta); err != nil {
-		zap.L().Sugar().Errorf("unmarshal config file error %v", err)
+	if err := metaStore.Get(containerName, meta); err != nil {
+		zap.L().Sugar().Errorf("read the config file error %v", err)
 		return nil, err
 	}
 	return meta, nil
 }
 
-func ListContainer() {
-	dirPath := fmt.Sprintf(DefaultInfoPath, "")
-	dirPath = dirPath[:len(dirPath)-1]
-
-	// ls dirPath
-	files, err := os.ReadDir(dirPath)
+// GetAllContainers get the information of all the containers
+func GetAllContainers() ([]*ContainerMeta, error) {
+	names, err := metaStore.Keys()
 	if err != nil {
-		zap.L().Sugar().Errorf("read the directory error %v", err)
-		return
+		return nil, err
 	}
 	containers := []*ContainerMeta{}
-	for _, file := range files {
-		meta, err := getContainerInfo(file)
-		if meta == nil && err == nil {
-			continue
-		}
-		if err != nil {
-			zap.L().Sugar().Error("get container information error")
+	for _, name := range names {
+		meta := new(ContainerMeta)
+		if err := metaStore.Get(name, meta); err != nil {
+			// the container is removed after listing
+			if !os.IsNotExist(err) {
+				zap.L().Sugar().Errorf("get container information error %v", err)
+			}
 			continue
 		}
 		containers = append(containers, meta)
 	}
-
-	w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
-	fmt.Fprint(w, "ID\tNAME\tPID\tSTATUS\tCOMMAND\tCREATED\n")
-	for _, item := range containers {
-		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n",
-			item.ID,
-			item.Name,
-			item.PID,
-			item.Status,
-			item.Command,
-			item.CreateAt.Format(time.DateTime),
-		)
-	}
-	if err := w.Flush(); err != nil {
-		zap.L().Sugar().Errorf("flush error %v", err)
-		return
-	}
+	return containers, nil
 }
 
-func getContainerInfo(file fs.DirEntry) (*ContainerMeta, error) {
-	if file.IsDir() {
-		cfgPath := fmt.Sprintf(DefaultInfoPath, file.Name())
-		f, err := os.ReadFile(filepath.Join(cfgPath, ConfigName))
-		if err != nil {
-			zap.L().Sugar().Errorf("read the container config file error %v", err)
-			return nil, err
-		}
-		// decode
-		containerMeta := new(ContainerMeta)
-		err = json.Unmarshal(f, containerMeta)
-		if err != nil {
-			zap.L().Sugar().Error("unmarshal json error %v", err)
-			return nil, err
-		}
-		return containerMeta, nil
+// GetContainerLog print the log of the container by the config, the follow
+// mode stops after the container exits
+func GetContainerLog(containerName string, cfg *LogsConfig) error {
+	meta, err := GetContainerByName(containerName)
+	if err != nil {
+		return fmt.Errorf("get container meta by container name error %v", err)
+	}
+	if meta.LogDriver != "" && meta.LogDriver != JSONFileLogDriver {
+		return fmt.Errorf("the log of container %s is sent by the log driver %s, only the %s log can be read", containerName, meta.LogDriver, JSONFileLogDriver)
 	}
-	return nil, nil
-}
-
-func GetContainerLog(containerName string) {
 	dirPath := fmt.Sprintf(DefaultInfoPath, containerName)
 	logPath := filepath.Join(dirPath, ContainerLog)
-	content, err := os.ReadFile(logPath)
+	stopped := func() bool {
+		meta, err := GetContainerByName(containerName)
+		if err != nil {
+			return true
+		}
+		RefreshContainerStatus(meta)
+		return meta.Status != RUNING && meta.Status != PAUSED && meta.Status != RESTARTING
+	}
+	err = FollowLog(logPath, cfg, func(entry *LogEntry) {
+		out := os.Stdout
+		if entry.Stream == LogStreamStderr {
+			out = os.Stderr
+		}
+		if cfg.Timestamps {
+			fmt.Fprintf(out, "%s %s", entry.Time.Format(time.RFC3339Nano), entry.Log)
+		} else {
+			fmt.Fprint(out, entry.Log)
+		}
+	}, stopped)
 	if err != nil {
-		zap.L().Sugar().Errorf("read the container %s error %v", containerName, err)
-		return
+		return fmt.Errorf("read the container %s log error %v", containerName, err)
 	}
-	fmt.Print(string(content))
+	return nil
 }
 
-func RemoveContainer(containerName string) {
+// GetStoppedContainer get the container which can be removed, the status is
+// refreshed since the process may die without the shim recording it
+func GetStoppedContainer(containerName string) (*ContainerMeta, error) {
 	meta, err := GetContainerByName(containerName)
 	if err != nil {
-		zap.L().Sugar().Errorf("get container meta by container name error %v", err)
-		return
+		return nil, fmt.Errorf("get container meta by container name error %v", err)
+	}
+	RefreshContainerStatus(meta)
+	if meta.Status != STOP && meta.Status != EXIT {
+		return nil, fmt.Errorf("container %s is %s, only stopped container can be removed", containerName, meta.Status)
 	}
+	return meta, nil
+}
 
-	if meta.Status != STOP {
-		zap.L().Sugar().Errorf("the container %s status isn't stopped", containerName)
-		return
+// RemoveContainer remove the config, the overlayfs and the cgroup of the
+// stopped container, the ip is released by the caller
+func RemoveContainer(containerName string) error {
+	meta, err := GetStoppedContainer(containerName)
+	if err != nil {
+		return err
 	}
 	err = DeleteConfig(containerName)
 	if err != nil {
-		zap.L().Sugar().Errorf("delete container config error %v", err)
-		return
+		return fmt.Errorf("delete container config error %v", err)
 	}
 	volume := strings.Split(meta.Volume, " ")
 	DeleteWorkSpace(meta.Image, containerName, volume)
+	// the container cgroup outlives the container process
+	if meta.CgroupPath != "" {
+		cgroup.NewCgroupManager(meta.CgroupPath).Destroy()
+	}
+	return nil
 }
 
-func StopContainer(containerName string) {
-	pid, err := getPidByContainerName(containerName)
+// StopContainer send the stop signal to the container, the container is
+// killed if it doesn't exit in the timeout. the state is updated after the
+// container process exits
+func StopContainer(containerName string, timeout time.Duration) error {
+	meta, err := GetContainerByName(containerName)
 	if err != nil {
-		zap.L().Sugar().Errorf("get pid by container name error %v", err)
-		return
+		return fmt.Errorf("get container meta by container name error %v", err)
 	}
-
-	err = syscall.Kill(pid, syscall.SIGTERM)
+	RefreshContainerStatus(meta)
+	// the restarting container has no process, the shim gives up restarting it
+	if meta.Status == RESTARTING {
+		_, err := updateContainerMeta(containerName, func(meta *ContainerMeta) error {
+			if meta.Status != RESTARTING {
+				return fmt.Errorf("container %s is %s, stop it again", containerName, meta.Status)
+			}
+			meta.Status = STOP
+			meta.ManuallyStopped = true
+			return nil
+		})
+		return err
+	}
+	// the pid of the stopped container is -1, kill(-1) signals every process
+	if meta.Status != RUNING && meta.Status != PAUSED {
+		return fmt.Errorf("container %s is %s, it can't be stopped", containerName, meta.Status)
+	}
+	stopSignal := meta.StopSignal
+	if stopSignal == "" {
+		stopSignal = DefaultStopSignal
+	}
+	signal, err := ParseSignal(stopSignal)
 	if err != nil {
-		zap.L().Sugar().Errorf("kill the pid %d error %v", pid, err)
-		return
+		return err
+	}
+	// the shim doesn't restart the container stopped by user
+	_, err = updateContainerMeta(containerName, func(meta *ContainerMeta) error {
+		meta.ManuallyStopped = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	pid := meta.PID
+	// the signal can't be handled until the container is thawed
+	if meta.Status == PAUSED {
+		if err := cgroup.NewCgroupManager(meta.CgroupPath).Freeze(false); err != nil {
+			return fmt.Errorf("unpause the container %s error %v", containerName, err)
+		}
+	}
+
+	if err := syscall.Kill(pid, signal); err != nil {
+		return fmt.Errorf("kill the pid %d error %v", pid, err)
+	}
+	// the container process is pid 1 in its namespace, the kernel ignores the
+	// signals it doesn't handle except SIGKILL
+	if !WaitProcessExit(pid, timeout) {
+		zap.L().Sugar().Warnf("container %s doesn't exit in %v, kill it", containerName, timeout)
+		if err := syscall.Kill(pid, syscall.SIGKILL); err != nil {
+			return fmt.Errorf("kill the pid %d error %v", pid, err)
+		}
+		if !WaitProcessExit(pid, stopWaitTimeout) {
+			return fmt.Errorf("container %s doesn't exit after SIGKILL", containerName)
+		}
+	}
+
+	// the shim records the exit code, the container without shim is recorded here
+	WaitProcessExit(meta.ShimPID, stopWaitTimeout)
+	_, err = updateContainerMeta(containerName, func(meta *ContainerMeta) error {
+		if meta.PID == pid && (meta.Status == RUNING || meta.Status == PAUSED) {
+			markExited(meta, -1, false)
+		}
+		return nil
+	})
+	// the foreground container is removed after it exits
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
 	}
+	return err
+}
 
-	// override the config.json
+// KillContainer send the signal to the container process, the exit is
+// recorded by the shim
+func KillContainer(containerName string, signal syscall.Signal) error {
 	meta, err := GetContainerByName(containerName)
 	if err != nil {
-		zap.L().Sugar().Errorf("get container meta by container name error %v", err)
-		return
+		return fmt.Errorf("get container meta by container name error %v", err)
 	}
-	meta.Status = STOP
-	meta.PID = -1
-	cfg, err := json.Marshal(meta)
-	if err != nil {
-		zap.L().Sugar().Errorf("marshal container information error %v", err)
-		return
+	RefreshContainerStatus(meta)
+	if meta.Status != RUNING && meta.Status != PAUSED {
+		return fmt.Errorf("container %s is %s, it can't be killed", containerName, meta.Status)
 	}
-	dirPath := fmt.Sprintf(DefaultInfoPath, containerName)
-	cfgPath := filepath.Join(dirPath, ConfigName)
-	err = os.WriteFile(cfgPath, cfg, 0622)
-	if err != nil {
-		zap.L().Sugar().Errorf("write file error %v", err)
-		return
+	if err := syscall.Kill(meta.PID, signal); err != nil {
+		return fmt.Errorf("kill the pid %d error %v", meta.PID, err)
 	}
+	return nil
+}
+
+// UpdateContainer change the resource limits of the container, the limits of
+// a running container are applied to its cgroup immediately
+func UpdateContainer(containerName string, cfg *subsystems.ResourceConfig) error {
+	_, err := updateContainerMeta(containerName, func(meta *ContainerMeta) error {
+		resource := &subsystems.ResourceConfig{}
+		if meta.Resource != nil {
+			resource = meta.Resource
+		}
+		resource.Merge(cfg)
+		if err := resource.Validate(); err != nil {
+			return err
+		}
+
+		if (meta.Status == RUNING || meta.Status == PAUSED) && meta.CgroupPath != "" {
+			if err := cgroup.NewCgroupManager(meta.CgroupPath).Set(resource); err != nil {
+				return fmt.Errorf("update the cgroup of container %s error %v", containerName, err)
+			}
+		}
+		meta.Resource = resource
+		return nil
+	})
+	return err
+}
+
+// PauseContainer suspend all the processes of the container by the cgroup freezer
+func PauseContainer(containerName string) error {
+	_, err := updateContainerMeta(containerName, func(meta *ContainerMeta) error {
+		if meta.Status != RUNING {
+			return fmt.Errorf("the container %s isn't running", containerName)
+		}
+		if err := cgroup.NewCgroupManager(meta.CgroupPath).Freeze(true); err != nil {
+			return fmt.Errorf("freeze the container %s error %v", containerName, err)
+		}
+		meta.Status = PAUSED
+		return nil
+	})
+	return err
+}
+
+// UnpauseContainer resume all the processes of the paused container
+func UnpauseContainer(containerName string) error {
+	_, err := updateContainerMeta(containerName, func(meta *ContainerMeta) error {
+		if meta.Status != PAUSED {
+			return fmt.Errorf("the container %s isn't paused", containerName)
+		}
+		if err := cgroup.NewCgroupManager(meta.CgroupPath).Freeze(false); err != nil {
+			return fmt.Errorf("thaw the container %s error %v", containerName, err)
+		}
+		meta.Status = RUNING
+		return nil
+	})
+	return err
 }
@@ -2,96 +2,79 @@ package container
 
 import (
 	"crypto/sha256"
-	"encoding/json"
 	"fmt"
+	"mini-docker/store"
 	"net"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
-// record the container information
-func RecordContainer(pid int, args, volume, port []string, containerName, containerID, imageName string) (string, error) {
-	createAt := time.Now()
-	command := strings.Join(args, " ")
+// the schema version of config.json
+const metaSchemaVersion = 1
 
-	containerMeta := &ContainerMeta{
-		ID:       containerID,
-		PID:      pid,
-		Command:  command,
-		Name:     containerName,
-		Status:   RUNING,
-		CreateAt: createAt,
-		Volume:   strings.Join(volume, " "),
-		Port:     strings.Join(port, " "),
-		Image:    imageName,
-	}
-
-	// write to config
-	cfg, err := json.Marshal(containerMeta)
-	if err != nil {
-		zap.L().Sugar().Errorf("marshal container information error %v", err)
-		return "", err
-	}
+// the config.json of the containers
+var metaStore = store.New(filepath.Clean(fmt.Sprintf(DefaultInfoPath, "")), "%s/"+ConfigName, metaSchemaVersion, nil)
 
-	dirPath := fmt.Sprintf(DefaultInfoPath, containerName)
-	if err := os.MkdirAll(dirPath, 0755); err != nil {
-		zap.L().Sugar().Errorf("mkdir dir %s error %v", dirPath, err)
-		return "", err
-	}
-
-	cfgPath := filepath.Join(dirPath, ConfigName)
-	f, err := os.Create(cfgPath)
+// record the container information, the creation time and the ip are kept
+// when the container is restarted
+func RecordContainer(containerMeta *ContainerMeta) error {
+	containerMeta.CreateAt = time.Now()
+	containerMeta.StartAt = containerMeta.CreateAt
+	containerMeta.Status = RUNING
+	old := &ContainerMeta{}
+	err := metaStore.Update(containerMeta.Name, old, func() error {
+		if old.ID == containerMeta.ID {
+			containerMeta.CreateAt = old.CreateAt
+			containerMeta.IP = old.IP
+		}
+		*old = *containerMeta
+		return nil
+	})
 	if err != nil {
-		zap.L().Sugar().Errorf("create config file error %v", err)
-		return "", err
-	}
-	defer f.Close()
-
-	if _, err := f.Write(cfg); err != nil {
-		zap.L().Sugar().Errorf("write config file error %v", err)
-		return "", err
+		zap.L().Sugar().Errorf("record container information error %v", err)
 	}
-	return containerName, nil
+	return err
 }
 
 func WriteNetwork(ip net.IPNet, containerName string) error {
-	containerMeta, err := GetContainerByName(containerName)
-	if err != nil {
-		zap.L().Sugar().Errorf("get container information error %v", err)
-		return err
-	}
-	// write network
-	containerMeta.IP = ip.String()
-	// write to config
-	cfg, err := json.Marshal(containerMeta)
+	_, err := updateContainerMeta(containerName, func(meta *ContainerMeta) error {
+		meta.IP = ip.String()
+		return nil
+	})
 	if err != nil {
-		zap.L().Sugar().Errorf("marshal container information error %v", err)
-		return err
+		zap.L().Sugar().Errorf("write network of container %s error %v", containerName, err)
 	}
+	return err
+}
 
-	dirPath := fmt.Sprintf(DefaultInfoPath, containerName)
-	cfgPath := filepath.Join(dirPath, ConfigName)
-	f, err := os.OpenFile(cfgPath, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		zap.L().Sugar().Errorf("openfile %s error %v", cfgPath, err)
-		return err
-	}
-	defer f.Close()
-	_, err = f.Write(cfg)
+// change the config.json of the container by fn with the lock held, fn gets
+// the latest meta and mustn't update the container again. the updated meta
+// is returned
+func updateContainerMeta(containerName string, fn func(meta *ContainerMeta) error) (*ContainerMeta, error) {
+	meta := &ContainerMeta{}
+	err := metaStore.Update(containerName, meta, func() error {
+		// the container is removed
+		if meta.ID == "" {
+			return fmt.Errorf("no such container %s: %w", containerName, os.ErrNotExist)
+		}
+		return fn(meta)
+	})
 	if err != nil {
-		zap.L().Sugar().Errorf("write new file %s error %v", cfgPath, err)
-		return err
+		return nil, err
 	}
-	return nil
+	return meta, nil
 }
 
 func DeleteConfig(containerName string) error {
 	dirPath := fmt.Sprintf(DefaultInfoPath, containerName)
+	// wait for the running update
+	if unlock, err := metaStore.Lock(containerName); err == nil {
+		defer unlock()
+	}
 	return os.RemoveAll(dirPath)
 }
 
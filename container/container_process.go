@@ -2,7 +2,6 @@ package container
 
 import (
 	"errors"
-	"fmt"
 	"mini-docker/config"
 	"os"
 	"os/exec"
@@ -15,7 +14,10 @@ const prgPath = "/proc/self/exe"
 var ErrCreateWorkSpace = errors.New("create overlayfs work space error")
 
 // parent process
-func NewParentProcess(tty bool, imageName, containerName string, env, volumePath []string) (*exec.Cmd, *os.File, error) {
+// the init config is sent to the container through the returned pipe, the
+// stdio of the container is set by the caller. the workspace of the existing
+// container is reused
+func NewParentProcess(imageName, containerName string, volumePath []string, existing bool) (*exec.Cmd, *os.File, error) {
 	r, w, err := createPipe()
 	if err != nil {
 		return nil, nil, err
@@ -27,30 +29,12 @@ func NewParentProcess(tty bool, imageName, containerName string, env, volumePath
 		syscall.CLONE_NEWPID | syscall.CLONE_NEWNS,
 	}
 
-	if tty {
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-	} else {
-		dirPath := fmt.Sprintf(DefaultInfoPath, containerName)
-		if err := os.MkdirAll(dirPath, 0644); err != nil {
-			return nil, nil, err
-		}
-		logPath := filepath.Join(dirPath, ContainerLog)
-		f, err := os.Create(logPath)
-		if err != nil {
-			return nil, nil, err
-		}
-		cmd.Stdout = f
-	}
-
-	err = NewWorkSpace(imageName, containerName, volumePath)
+	err = NewWorkSpace(imageName, containerName, volumePath, existing)
 	if err != nil {
 		return nil, nil, err
 	}
 	cmd.ExtraFiles = []*os.File{r}
 	cmd.Dir = filepath.Join(config.ContainerPath, containerName, "merged")
-	cmd.Env = append(os.Environ(), env...)
 	return cmd, w, nil
 }
 
@@ -0,0 +1,101 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// the default key sequence of detaching from the container
+const DefaultDetachKeys = "ctrl-p,ctrl-q"
+
+// ErrDetached the client typed the detach keys
+var ErrDetached = errors.New("detached from the container")
+
+// ParseDetachKeys parse the key sequence, e.g. ctrl-p,ctrl-q. a key is a
+// single character or ctrl-<value>, the value is a letter or one of @[\]^_
+func ParseDetachKeys(keys string) ([]byte, error) {
+	sequence := []byte{}
+	for _, key := range strings.Split(keys, ",") {
+		if len(key) == 1 {
+			sequence = append(sequence, key[0])
+			continue
+		}
+		value, ok := strings.CutPrefix(strings.ToLower(key), "ctrl-")
+		if !ok || len(value) != 1 {
+			return nil, fmt.Errorf("invalid detach keys %q", keys)
+		}
+		switch c := value[0]; {
+		case c >= 'a' && c <= 'z':
+			sequence = append(sequence, c-'a'+1)
+		case strings.IndexByte("@[\\]^_", c) >= 0:
+			// ctrl-@ is 0, ctrl-[ is ESC and so on
+			sequence = append(sequence, c-'@')
+		default:
+			return nil, fmt.Errorf("invalid detach keys %q", keys)
+		}
+	}
+	return sequence, nil
+}
+
+// DetachReader read from the terminal and return ErrDetached once the detach
+// keys are typed. the keys are held back until they can't match the sequence
+type DetachReader struct {
+	r    io.Reader
+	keys []byte
+	// the number of the matched keys
+	matched int
+	pending []byte
+	buf     []byte
+	err     error
+}
+
+func NewDetachReader(r io.Reader, keys []byte) *DetachReader {
+	return &DetachReader{r: r, keys: keys, buf: make([]byte, 1024)}
+}
+
+func (d *DetachReader) Read(p []byte) (int, error) {
+	if len(d.keys) == 0 {
+		return d.r.Read(p)
+	}
+	for len(d.pending) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		n, err := d.r.Read(d.buf)
+		d.scan(d.buf[:n])
+		if err != nil && d.err == nil {
+			// the held keys are input when the sequence can't be completed
+			d.pending = append(d.pending, d.keys[:d.matched]...)
+			d.matched = 0
+			d.err = err
+		}
+	}
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+func (d *DetachReader) scan(data []byte) {
+	for _, c := range data {
+		if d.err != nil {
+			return
+		}
+		if c == d.keys[d.matched] {
+			d.matched++
+			if d.matched == len(d.keys) {
+				d.err = ErrDetached
+			}
+			continue
+		}
+		// flush the held keys, the current key may start a new match
+		d.pending = append(d.pending, d.keys[:d.matched]...)
+		d.matched = 0
+		if c == d.keys[0] {
+			d.matched = 1
+			continue
+		}
+		d.pending = append(d.pending, c)
+	}
+}
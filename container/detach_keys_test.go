@@ -0,0 +1,54 @@
+package container
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDetachKeys(t *testing.T) {
+	assert := assert.New(t)
+	cases := map[string][]byte{
+		"ctrl-p,ctrl-q": {16, 17},
+		"ctrl-@":        {0},
+		"ctrl-[,a":      {27, 'a'},
+		"CTRL-A,ctrl-_": {1, 31},
+	}
+	for keys, expected := range cases {
+		value, err := ParseDetachKeys(keys)
+		assert.Nil(err, "parse %s should return nil", keys)
+		assert.Equal(expected, value, "parse %s", keys)
+	}
+	for _, keys := range []string{"", "ctrl-", "ctrl-1", "ctrl-ab", "alt-a", "ab"} {
+		_, err := ParseDetachKeys(keys)
+		assert.NotNil(err, "parse %s should return error", keys)
+	}
+}
+
+func TestDetachReader(t *testing.T) {
+	assert := assert.New(t)
+	keys := []byte{16, 17}
+	cases := map[string]struct {
+		output string
+		err    error
+	}{
+		"hello":             {"hello", io.EOF},
+		"ab\x10\x17cd":      {"ab\x10\x17cd", io.EOF},
+		"ab\x10\x10\x11cd":  {"ab\x10", ErrDetached},
+		"ab\x10\x11cd":      {"ab", ErrDetached},
+		"\x10":              {"\x10", io.EOF},
+		"\x10x\x10\x11rest": {"\x10x", ErrDetached},
+	}
+	for input, expected := range cases {
+		r := NewDetachReader(strings.NewReader(input), keys)
+		output, err := io.ReadAll(r)
+		if expected.err == io.EOF {
+			assert.Nil(err, "read %q", input)
+		} else {
+			assert.Equal(expected.err, err, "read %q", input)
+		}
+		assert.Equal(expected.output, string(output), "read %q", input)
+	}
+}
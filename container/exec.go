@@ -0,0 +1,264 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mini-docker/cgroup"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// the file descriptors of the exec process, 0-2 are stdio
+const (
+	execConfigFd = 3
+	execReadyFd  = 4
+	// the default path of the exec command
+	defaultPath = "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+)
+
+// ExecOptions the options of the exec command
+type ExecOptions struct {
+	Args        []string
+	Env         []string
+	Workdir     string
+	User        string
+	TTY         bool
+	Interactive bool
+	Detach      bool
+}
+
+// the configuration sent to the exec process in the container
+type ExecConfig struct {
+	Args []string `json:"args"`
+	Env  []string `json:"env"`
+	Cwd  string   `json:"cwd"`
+	User string   `json:"user"`
+	TTY  bool     `json:"tty"`
+}
+
+// ExecContainer run the command in the running container and return the exit
+// code of the command, a pty is allocated for the command if tty is true. the
+// detached command runs in the background, its exit code is always 0
+func ExecContainer(containerName string, opts *ExecOptions) (int, error) {
+	meta, err := GetContainerByName(containerName)
+	if err != nil {
+		return -1, fmt.Errorf("get container meta by container name error %v", err)
+	}
+	RefreshContainerStatus(meta)
+	if meta.Status != RUNING {
+		return -1, fmt.Errorf("the container %s is %s, only running container can exec", containerName, meta.Status)
+	}
+	zap.L().Sugar().Infof("exec %q in container %s", opts.Args, containerName)
+	cfg := &ExecConfig{
+		Args: opts.Args,
+		Env:  execEnv(meta, opts),
+		Cwd:  opts.Workdir,
+		User: opts.User,
+		TTY:  opts.TTY,
+	}
+	// the settings of the container are the defaults
+	if cfg.Cwd == "" {
+		cfg.Cwd = meta.Workdir
+	}
+	if cfg.User == "" {
+		cfg.User = meta.User
+	}
+
+	configReader, configWriter, err := os.Pipe()
+	if err != nil {
+		return -1, err
+	}
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return -1, err
+	}
+	defer readyReader.Close()
+	cmd := exec.Command(prgPath, "exec")
+	cmd.ExtraFiles = []*os.File{configReader, readyWriter}
+	// the exec process enters the namespaces by the pid before the go runtime starts
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", ENV_EXEC_PID, meta.PID))
+	var console *Console
+	switch {
+	case opts.Detach:
+		// the detached command outlives the cli
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	case opts.TTY:
+		if console, err = NewConsole(); err != nil {
+			return -1, err
+		}
+		console.Attach(cmd)
+	default:
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+	if opts.Interactive && !opts.TTY {
+		cmd.Stdin = os.Stdin
+	}
+	if err := cmd.Start(); err != nil {
+		return -1, fmt.Errorf("start exec process error %v", err)
+	}
+	configReader.Close()
+	readyWriter.Close()
+	if console != nil {
+		defer console.Close()
+		if err := console.Start(opts.Interactive); err != nil {
+			zap.L().Sugar().Warnf("exec container %s error %v", containerName, err)
+		}
+	}
+	// the command is limited by the cgroup of the container, it mustn't run
+	// without the limits of the container
+	if meta.CgroupPath != "" {
+		cgroupManager := cgroup.NewCgroupManager(meta.CgroupPath)
+		if meta.Resource != nil {
+			cgroupManager.Resource = *meta.Resource
+		}
+		if err := cgroupManager.Apply(cmd.Process.Pid); err != nil {
+			configWriter.Close()
+			cmd.Process.Kill()
+			cmd.Wait()
+			return -1, fmt.Errorf("apply the cgroup of container %s error %v", containerName, err)
+		}
+	}
+	err = json.NewEncoder(configWriter).Encode(cfg)
+	configWriter.Close()
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return -1, fmt.Errorf("send exec config error %v", err)
+	}
+	// the exec process closes the pipe without any message after the command started
+	msg, err := io.ReadAll(readyReader)
+	if err != nil {
+		return -1, fmt.Errorf("read exec process error %v", err)
+	}
+	if len(msg) != 0 {
+		cmd.Wait()
+		return exitCode(cmd.ProcessState), fmt.Errorf("exec in container %s error: %s", containerName, msg)
+	}
+	if opts.Detach {
+		return 0, cmd.Process.Release()
+	}
+	cmd.Wait()
+	return exitCode(cmd.ProcessState), nil
+}
+
+// ExecInit replace the process with the command, the process has entered the
+// namespaces of the container by nsenter, whose parent waits for the command
+// and exits with its exit code
+func ExecInit() error {
+	syscall.CloseOnExec(execReadyFd)
+	ready := os.NewFile(uintptr(execReadyFd), "ready")
+	// the pipe is closed by exec if the command starts
+	code, err := execCommand()
+	ready.WriteString(err.Error())
+	ready.Close()
+	os.Exit(code)
+	return nil
+}
+
+// exec the command by the exec config, return the exit code and the error if
+// it fails
+func execCommand() (int, error) {
+	pipe := os.NewFile(uintptr(execConfigFd), "pipe")
+	cfg := &ExecConfig{}
+	err := json.NewDecoder(pipe).Decode(cfg)
+	pipe.Close()
+	if err != nil {
+		return 1, fmt.Errorf("read exec config error %v", err)
+	}
+	if len(cfg.Args) == 0 {
+		return 1, fmt.Errorf("the command is empty")
+	}
+	var cred *syscall.Credential
+	if cfg.User != "" {
+		if cred, err = lookupUser(cfg.User); err != nil {
+			return 1, fmt.Errorf("look up user %s error %v", cfg.User, err)
+		}
+	}
+	if cfg.Cwd != "" {
+		if err := syscall.Chdir(cfg.Cwd); err != nil {
+			return 1, fmt.Errorf("chdir %s error %v", cfg.Cwd, err)
+		}
+	}
+	// the command is looked up by its own environment
+	os.Clearenv()
+	for _, env := range cfg.Env {
+		if key, value, ok := strings.Cut(env, "="); ok {
+			os.Setenv(key, value)
+		}
+	}
+	path, err := exec.LookPath(cfg.Args[0])
+	if err != nil {
+		return 127, err
+	}
+	if cfg.TTY {
+		// the command leads a session with the pty as the controlling
+		// terminal, so the shell can do job control
+		if _, err := syscall.Setsid(); err != nil {
+			return 1, fmt.Errorf("setsid error %v", err)
+		}
+		if err := ioctl(os.Stdin.Fd(), syscall.TIOCSCTTY, 1); err != nil {
+			return 1, fmt.Errorf("set controlling terminal error %v", err)
+		}
+	}
+	if cred != nil {
+		if err := setUser(cred); err != nil {
+			return 1, err
+		}
+	}
+	if err := syscall.Exec(path, cfg.Args, cfg.Env); err != nil {
+		return 126, fmt.Errorf("exec %s error %v", path, err)
+	}
+	return 0, nil
+}
+
+// the environment of the exec command, the environment of the container is
+// overridden by the exec options
+func execEnv(meta *ContainerMeta, opts *ExecOptions) []string {
+	env := []string{defaultPath}
+	if opts.TTY {
+		env = append(env, "TERM=xterm")
+	}
+	hostname := meta.Hostname
+	if hostname == "" && len(meta.ID) >= 12 {
+		hostname = meta.ID[:12]
+	}
+	env = append(env, "HOSTNAME="+hostname)
+	return mergeEnv(mergeEnv(env, meta.Env), opts.Env)
+}
+
+// merge the environment by the keys, the later value overrides the earlier
+func mergeEnv(env, overrides []string) []string {
+	merged := append([]string{}, env...)
+	index := make(map[string]int)
+	for i, e := range merged {
+		key, _, _ := strings.Cut(e, "=")
+		index[key] = i
+	}
+	for _, e := range overrides {
+		key, _, _ := strings.Cut(e, "=")
+		if i, ok := index[key]; ok {
+			merged[i] = e
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// the exit code of the process, 128 + signal if it's killed by a signal
+func exitCode(state *os.ProcessState) int {
+	if state == nil {
+		return -1
+	}
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return state.ExitCode()
+}
@@ -0,0 +1,17 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeEnv(t *testing.T) {
+	assert := assert.New(t)
+	env := []string{"PATH=/bin", "HOSTNAME=abc"}
+	merged := mergeEnv(env, []string{"FOO=bar", "PATH=/usr/bin", "EMPTY="})
+	assert.Equal([]string{"PATH=/usr/bin", "HOSTNAME=abc", "FOO=bar", "EMPTY="}, merged)
+	// the original environment isn't changed
+	assert.Equal([]string{"PATH=/bin", "HOSTNAME=abc"}, env)
+	assert.Equal(env, mergeEnv(env, nil))
+}
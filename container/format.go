@@ -0,0 +1,40 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// the functions of the go template, e.g. {{json .Resource}}
+var formatFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// ParseFormat parse the go template of the --format flag
+func ParseFormat(format string) (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(formatFuncs).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("parse format %q error %v", format, err)
+	}
+	return tmpl, nil
+}
+
+// ExecuteFormat write the object by the template, a newline follows every object
+func ExecuteFormat(w io.Writer, tmpl *template.Template, v interface{}) error {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, v); err != nil {
+		return fmt.Errorf("execute format error %v", err)
+	}
+	sb.WriteString("\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
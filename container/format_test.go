@@ -0,0 +1,24 @@
+package container
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	assert := assert.New(t)
+	meta := &ContainerMeta{Name: "web", IP: "10.0.0.2/24", Env: []string{"A=1", "B=2"}}
+	tmpl, err := ParseFormat(`{{.Name}} {{.IP}} {{join .Env ","}} {{json .Args}} {{upper .Name}}`)
+	assert.Nil(err)
+	var sb strings.Builder
+	assert.Nil(ExecuteFormat(&sb, tmpl, meta))
+	assert.Equal("web 10.0.0.2/24 A=1,B=2 null WEB\n", sb.String())
+
+	_, err = ParseFormat("{{.Name")
+	assert.NotNil(err)
+	tmpl, err = ParseFormat("{{.Missing}}")
+	assert.Nil(err)
+	assert.NotNil(ExecuteFormat(&sb, tmpl, meta))
+}
@@ -1,8 +1,8 @@
 package container
 
 import (
+	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -11,30 +11,66 @@ import (
 	"go.uber.org/zap"
 )
 
+// ContainerInit set up the container and run the user command, the user command
+// replaces the init process unless the init mode is enabled
 func ContainerInit() error {
-	receiveCMD, err := readCMD()
+	cfg, err := readInitConfig()
 	if err != nil {
-		zap.L().Sugar().Errorf("read command from pipe error %v", err)
-		return fmt.Errorf("read command from pipe error %v", err)
+		zap.L().Sugar().Errorf("read init config from pipe error %v", err)
+		return fmt.Errorf("read init config from pipe error %v", err)
 	}
-	if len(receiveCMD) == 0 {
+	if len(cfg.Args) == 0 {
 		zap.L().Sugar().Error("run container get user command error")
 		return fmt.Errorf("run container get user command error")
 	}
 
-	if err := setMount(); err != nil {
+	if err := setMount(cfg.Mounts); err != nil {
 		zap.L().Sugar().Errorf("set mount is error %v", err)
 		return fmt.Errorf("container set mount error")
 	}
+	if cfg.Hostname != "" {
+		if err := syscall.Sethostname([]byte(cfg.Hostname)); err != nil {
+			return fmt.Errorf("set hostname error %v", err)
+		}
+	}
+	if cfg.Cwd != "" {
+		if err := os.MkdirAll(cfg.Cwd, 0755); err != nil {
+			return fmt.Errorf("create working directory %s error %v", cfg.Cwd, err)
+		}
+		if err := syscall.Chdir(cfg.Cwd); err != nil {
+			return fmt.Errorf("chdir %s error %v", cfg.Cwd, err)
+		}
+	}
+	var cred *syscall.Credential
+	if cfg.User != "" {
+		if cred, err = lookupUser(cfg.User); err != nil {
+			return fmt.Errorf("look up user %s error %v", cfg.User, err)
+		}
+	}
+	// the user command looks up the path by its own environment
+	os.Clearenv()
+	for _, env := range cfg.Env {
+		if key, value, ok := strings.Cut(env, "="); ok {
+			os.Setenv(key, value)
+		}
+	}
 
-	path, err := exec.LookPath(receiveCMD[0])
+	path, err := exec.LookPath(cfg.Args[0])
 	if err != nil {
 		zap.L().Sugar().Errorf("exec look path error %v", err)
 		return err 
 	}
 	zap.L().Sugar().Infof("find path %s", path)
+	if cfg.Init {
+		return runAsInit(path, cfg.Args, os.Environ(), cred)
+	}
+	if cred != nil {
+		if err := setUser(cred); err != nil {
+			return err
+		}
+	}
 	// override
-	if err := syscall.Exec(path, receiveCMD, os.Environ()); err != nil {
+	if err := syscall.Exec(path, cfg.Args, os.Environ()); err != nil {
 		zap.L().Sugar().Error(err.Error())
 		return err
 	}
@@ -73,7 +109,7 @@ func pivotRoot(root string) error {
 	return nil
 }
 
-func setMount() error {
+func setMount(mounts []Mount) error {
 	pwd, err := os.Getwd()
 	if err != nil {
 		return err
@@ -83,17 +119,25 @@ func setMount() error {
 		return err
 	}
 
-	defaultMountFlag := syscall.MS_NOEXEC | syscall.MS_NOSUID | syscall.MS_NODEV
-	syscall.Mount("proc", "/proc", "proc", uintptr(defaultMountFlag), "")
-	syscall.Mount("tmpfs", "/dev", "tmpfs", syscall.MS_STRICTATIME | syscall.MS_NOSUID, "mode=755")
+	for _, m := range mounts {
+		if err := os.MkdirAll(m.Target, 0755); err != nil {
+			return fmt.Errorf("mkdir %s error: %v", m.Target, err)
+		}
+		if err := syscall.Mount(m.Source, m.Target, m.Type, m.Flags, m.Data); err != nil {
+			return fmt.Errorf("mount %s to %s error: %v", m.Source, m.Target, err)
+		}
+	}
 	return nil
 }
 
-func readCMD() ([]string, error) {
+// read the init config sent by the parent process, the parent closes the
+// pipe after writing
+func readInitConfig() (*InitConfig, error) {
 	pipe := os.NewFile(uintptr(3), "pipe")
-	msg, err := io.ReadAll(pipe)
-	if err != nil {
-		return nil, err 
+	defer pipe.Close()
+	cfg := &InitConfig{}
+	if err := json.NewDecoder(pipe).Decode(cfg); err != nil {
+		return nil, err
 	}
-	return strings.Split(string(msg), " "), nil
-}
\ No newline at end of file
+	return cfg, nil
+}
@@ -0,0 +1,165 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"mini-docker/cgroup/subsystems"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// the default log driver, the log can be read by the logs command
+	JSONFileLogDriver = "json-file"
+
+	LogStreamStdout = "stdout"
+	LogStreamStderr = "stderr"
+	// the log options of the json-file log
+	LogOptMaxSize = "max-size"
+	LogOptMaxFile = "max-file"
+)
+
+// LogEntry a line of the container log, it's compatible with the json-file
+// log of docker
+type LogEntry struct {
+	Log    string    `json:"log"`
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+}
+
+// LogOptions the rotation of the json-file log. the log is rotated when it
+// exceeds MaxSize, at most MaxFile files are kept
+type LogOptions struct {
+	// the max size of a log file, 0 means unlimited
+	MaxSize int64
+	MaxFile int
+}
+
+// ParseLogOptions parse the key=value log options, they are validated by the
+// log driver
+func ParseLogOptions(opts []string) (map[string]string, error) {
+	logOpts := make(map[string]string)
+	for _, opt := range opts {
+		key, value, ok := strings.Cut(opt, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid log option %q, the format is key=value", opt)
+		}
+		logOpts[key] = value
+	}
+	return logOpts, nil
+}
+
+// NewLogOptions get the rotation of the json-file log from the log options
+func NewLogOptions(logOpts map[string]string) (*LogOptions, error) {
+	opts := &LogOptions{MaxFile: 1}
+	for key, value := range logOpts {
+		switch key {
+		case LogOptMaxSize:
+			size, err := subsystems.ParseBytes(value)
+			if err != nil || size == 0 {
+				return nil, fmt.Errorf("invalid %s %q", LogOptMaxSize, value)
+			}
+			opts.MaxSize = int64(size)
+		case LogOptMaxFile:
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid %s %q, it must be a positive integer", LogOptMaxFile, value)
+			}
+			opts.MaxFile = count
+		default:
+			return nil, fmt.Errorf("unknown log option %q", key)
+		}
+	}
+	if opts.MaxFile > 1 && opts.MaxSize == 0 {
+		return nil, fmt.Errorf("%s requires %s", LogOptMaxFile, LogOptMaxSize)
+	}
+	return opts, nil
+}
+
+// JSONLogWriter write the log entries of the container as json lines
+type JSONLogWriter struct {
+	mu   sync.Mutex
+	path string
+	opts *LogOptions
+	file *os.File
+	size int64
+}
+
+// NewJSONLogWriter open the log to append, the log of the restarted container
+// follows the previous one
+func NewJSONLogWriter(path string, opts *LogOptions) (*JSONLogWriter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &JSONLogWriter{path: path, opts: opts, file: file, size: info.Size()}, nil
+}
+
+// WriteEntry write the entry as a line, the log is rotated before the line if
+// it exceeds the max size
+func (w *JSONLogWriter) WriteEntry(entry *LogEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(line)) > w.opts.MaxSize {
+		if err := w.rotate(); err != nil {
+			return fmt.Errorf("rotate the log %s error %v", w.path, err)
+		}
+	}
+	n, err := w.file.Write(line)
+	w.size += int64(n)
+	return err
+}
+
+func (w *JSONLogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// rotate the log, container.log is renamed to container.log.1, and
+// container.log.1 is renamed to container.log.2 and so on
+func (w *JSONLogWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	for i := w.opts.MaxFile - 1; i > 0; i-- {
+		from := rotatedLogPath(w.path, i-1)
+		if err := os.Rename(from, rotatedLogPath(w.path, i)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	// the single log is recreated instead of truncated, so the follower
+	// reading the old log can find the new one
+	if w.opts.MaxFile == 1 {
+		if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w.file, w.size = file, 0
+	return nil
+}
+
+// the path of the rotated log, the index of the current log is 0
+func rotatedLogPath(path string, index int) string {
+	if index == 0 {
+		return path
+	}
+	return fmt.Sprintf("%s.%d", path, index)
+}
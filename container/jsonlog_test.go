@@ -0,0 +1,51 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLogOptions(t *testing.T) {
+	assert := assert.New(t)
+	logOpts, err := ParseLogOptions([]string{"max-size=1k", "max-file=3"})
+	assert.Nil(err)
+	opts, err := NewLogOptions(logOpts)
+	assert.Nil(err)
+	assert.Equal(&LogOptions{MaxSize: 1024, MaxFile: 3}, opts)
+
+	_, err = ParseLogOptions([]string{"max-size"})
+	assert.NotNil(err)
+	for _, opt := range []string{"max-size=0", "max-file=0", "max-file=2", "mode=blocking"} {
+		logOpts, err := ParseLogOptions([]string{opt})
+		assert.Nil(err)
+		_, err = NewLogOptions(logOpts)
+		assert.NotNil(err, "parse %s should return error", opt)
+	}
+}
+
+func TestJSONLogWriter(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), ContainerLog)
+	w, err := NewJSONLogWriter(path, &LogOptions{MaxSize: 100, MaxFile: 3})
+	assert.Nil(err)
+	now := time.Now().UTC()
+	for _, line := range []string{"a\n", "b\n", "c\n", "d\n"} {
+		assert.Nil(w.WriteEntry(&LogEntry{Log: line, Stream: LogStreamStdout, Time: now}))
+	}
+	assert.Nil(w.Close())
+
+	logs := ""
+	assert.Nil(ReadLog(path, func(entry *LogEntry) {
+		logs += entry.Log
+	}))
+	// the oldest entry is dropped by the rotation
+	assert.Equal("b\nc\nd\n", logs)
+	_, err = os.Stat(path + ".2")
+	assert.Nil(err, "the log should be rotated")
+	_, err = os.Stat(path + ".3")
+	assert.True(os.IsNotExist(err), "only 3 files are kept")
+}
@@ -0,0 +1,242 @@
+package container
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// the interval of checking the new output in follow mode
+const logFollowInterval = 200 * time.Millisecond
+
+// LogsConfig the options of reading the container log
+type LogsConfig struct {
+	// keep reading the new output until the container exits
+	Follow bool
+	// the number of the last lines, -1 means all
+	Tail int
+	// the time window of the lines, the zero value means no limit
+	Since time.Time
+	Until time.Time
+	// print the time of every line
+	Timestamps bool
+}
+
+// ParseLogTail parse the number of the last lines, all means all the lines
+func ParseLogTail(tail string) (int, error) {
+	if tail == "" || tail == "all" {
+		return -1, nil
+	}
+	n, err := strconv.Atoi(tail)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid tail %q, it must be a non-negative integer or all", tail)
+	}
+	return n, nil
+}
+
+// ParseLogTime parse the time of --since and --until. the value is a
+// timestamp(e.g. 2024-01-02T15:04:05Z, 2024-01-02), the unix seconds or a
+// duration before now(e.g. 10m)
+func ParseLogTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Unix(0, int64(seconds*float64(time.Second))), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+// the last entries of the log
+type logTail struct {
+	entries []*LogEntry
+	next    int
+	full    bool
+}
+
+func (t *logTail) add(entry *LogEntry) {
+	if len(t.entries) == 0 {
+		return
+	}
+	t.entries[t.next] = entry
+	t.next = (t.next + 1) % len(t.entries)
+	t.full = t.full || t.next == 0
+}
+
+func (t *logTail) flush(handler func(*LogEntry)) {
+	if t.full {
+		for _, entry := range t.entries[t.next:] {
+			handler(entry)
+		}
+	}
+	for _, entry := range t.entries[:t.next] {
+		handler(entry)
+	}
+}
+
+// FollowLog read the log of the container by the config. in follow mode, it
+// keeps reading the new output until stopped returns true
+func FollowLog(path string, cfg *LogsConfig, handler func(*LogEntry), stopped func() bool) error {
+	inWindow := func(entry *LogEntry) bool {
+		return (cfg.Since.IsZero() || !entry.Time.Before(cfg.Since)) &&
+			(cfg.Until.IsZero() || !entry.Time.After(cfg.Until))
+	}
+	filter := func(entry *LogEntry) {
+		if inWindow(entry) {
+			handler(entry)
+		}
+	}
+	// the last lines are held until the end of the log is read
+	read := filter
+	var tail *logTail
+	if cfg.Tail >= 0 {
+		tail = &logTail{entries: make([]*LogEntry, cfg.Tail)}
+		read = func(entry *LogEntry) {
+			if inWindow(entry) {
+				tail.add(entry)
+			}
+		}
+	}
+
+	// open the current log first, so the rotation during reading the rotated
+	// logs doesn't lose the output
+	current, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	// the current log is replaced after the rotation
+	defer func() {
+		current.Close()
+	}()
+	files := logFiles(path)
+	for _, file := range files[:len(files)-1] {
+		if err := readLogPath(file, read); err != nil {
+			return err
+		}
+	}
+	reader := bufio.NewReader(current)
+	pending, err := readLogEntries(reader, nil, read)
+	if err != nil {
+		return err
+	}
+	if tail != nil {
+		tail.flush(filter)
+	}
+	if !cfg.Follow {
+		if len(pending) > 0 {
+			filter(parseLogEntry(pending))
+		}
+		return nil
+	}
+
+	for {
+		// the output before the container exits is read in the next round
+		done := stopped() || (!cfg.Until.IsZero() && time.Now().After(cfg.Until))
+		if pending, err = readLogEntries(reader, pending, filter); err != nil {
+			return err
+		}
+		// continue with the new log after the rest of the rotated log is read
+		if rotated(current, path) {
+			if next, err := os.Open(path); err == nil {
+				if pending, err = readLogEntries(reader, pending, filter); err != nil {
+					next.Close()
+					return err
+				}
+				current.Close()
+				current, pending = next, nil
+				reader = bufio.NewReader(current)
+				continue
+			}
+		}
+		if done {
+			return nil
+		}
+		time.Sleep(logFollowInterval)
+	}
+}
+
+// ReadLog read all the log entries of the container from the oldest, the
+// rotated logs are read before the current log
+func ReadLog(path string, handler func(*LogEntry)) error {
+	return FollowLog(path, &LogsConfig{Tail: -1}, handler, nil)
+}
+
+// get the log files of the container from the oldest to the current
+func logFiles(path string) []string {
+	files := []string{path}
+	for i := 1; ; i++ {
+		rotated := rotatedLogPath(path, i)
+		if exist, _ := fileExists(rotated); !exist {
+			return files
+		}
+		files = append([]string{rotated}, files...)
+	}
+}
+
+func readLogPath(path string, handler func(*LogEntry)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		// the log may be rotated during reading
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	pending, err := readLogEntries(bufio.NewReader(f), nil, handler)
+	if err == nil && len(pending) > 0 {
+		handler(parseLogEntry(pending))
+	}
+	return err
+}
+
+// read the entries to the end of the log, the partial line at the end is
+// returned, it's completed by the next read
+func readLogEntries(reader *bufio.Reader, pending []byte, handler func(*LogEntry)) ([]byte, error) {
+	for {
+		line, err := reader.ReadBytes('\n')
+		line = append(pending, line...)
+		pending = nil
+		if err == io.EOF {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		handler(parseLogEntry(line))
+	}
+}
+
+// the line that isn't json is a stdout entry, it's written by the old version
+func parseLogEntry(line []byte) *LogEntry {
+	entry := &LogEntry{}
+	if json.Unmarshal(line, entry) != nil {
+		entry = &LogEntry{Log: string(line), Stream: LogStreamStdout}
+	}
+	return entry
+}
+
+// whether the opened log is rotated
+func rotated(f *os.File, path string) bool {
+	opened, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return !os.SameFile(opened, info)
+}
@@ -0,0 +1,58 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLogTime(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	cases := map[string]time.Time{
+		"":                     {},
+		"10m":                  now.Add(-10 * time.Minute),
+		"1704207845":           now,
+		"2024-01-02T15:04:05Z": now,
+	}
+	for value, expected := range cases {
+		parsed, err := ParseLogTime(value, now)
+		assert.Nil(err, "parse %s should return nil", value)
+		assert.True(expected.Equal(parsed), "parse %s", value)
+	}
+	_, err := ParseLogTime("yesterday", now)
+	assert.NotNil(err)
+
+	tail, err := ParseLogTail("all")
+	assert.Nil(err)
+	assert.Equal(-1, tail)
+	_, err = ParseLogTail("-1")
+	assert.NotNil(err)
+}
+
+func TestFollowLogTail(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), ContainerLog)
+	base := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	content := ""
+	for i, line := range []string{"a", "b", "c", "d"} {
+		content += `{"log":"` + line + `\n","stream":"stdout","time":"` + base.Add(time.Duration(i)*time.Second).Format(time.RFC3339Nano) + `"}` + "\n"
+	}
+	assert.Nil(os.WriteFile(path+".1", []byte(content[:len(content)/2]), 0644))
+	assert.Nil(os.WriteFile(path, []byte(content[len(content)/2:]), 0644))
+
+	read := func(cfg *LogsConfig) string {
+		logs := ""
+		assert.Nil(FollowLog(path, cfg, func(entry *LogEntry) {
+			logs += entry.Log
+		}, nil))
+		return logs
+	}
+	assert.Equal("a\nb\nc\nd\n", read(&LogsConfig{Tail: -1}))
+	assert.Equal("c\nd\n", read(&LogsConfig{Tail: 2}))
+	assert.Equal("", read(&LogsConfig{Tail: 0}))
+	assert.Equal("b\nc\n", read(&LogsConfig{Tail: -1, Since: base.Add(time.Second), Until: base.Add(2 * time.Second)}))
+}
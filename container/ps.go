@@ -0,0 +1,275 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"mini-docker/label"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// the keys of the ps filter
+const (
+	filterStatus   = "status"
+	filterName     = "name"
+	filterLabel    = "label"
+	filterAncestor = "ancestor"
+	filterNetwork  = "network"
+)
+
+const (
+	// the length of the truncated id
+	shortIDLength = 12
+	// the columns of ps, the table format prints the header
+	defaultPsFormat = "table {{.ID}}\t{{.Name}}\t{{.Image}}\t{{.PID}}\t{{.Status}}\t{{.Uptime}}\t{{.Restarts}}\t{{.Ports}}\t{{.IP}}\t{{.Command}}\t{{.Created}}"
+)
+
+// PsOptions the options of ps
+type PsOptions struct {
+	// show the stopped containers
+	All bool
+	// print the ids only
+	Quiet   bool
+	Filters []string
+	// table, json, or the go template, the template with the table prefix
+	// prints the header
+	Format string
+}
+
+// Filters the filters of ps, e.g. status=exited
+type Filters struct {
+	label.Filters
+}
+
+// the container in the output of ps
+type psRow struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Image    string `json:"image"`
+	PID      string `json:"pid"`
+	Status   string `json:"status"`
+	Uptime   string `json:"uptime"`
+	Restarts string `json:"restarts"`
+	Ports    string `json:"ports"`
+	IP       string `json:"ip"`
+	Network  string `json:"network"`
+	Command  string `json:"command"`
+	Created  string `json:"created"`
+	Labels   string `json:"labels"`
+	labels   map[string]string
+	header   bool
+}
+
+// Label get the value of the label, e.g. {{.Label "team"}}
+func (r *psRow) Label(key string) string {
+	if r.header {
+		return strings.ToUpper(key)
+	}
+	return r.labels[key]
+}
+
+// the header of the table format
+var psHeader = &psRow{
+	ID:       "CONTAINER ID",
+	Name:     "NAME",
+	Image:    "IMAGE",
+	PID:      "PID",
+	Status:   "STATUS",
+	Uptime:   "UPTIME",
+	Restarts: "RESTARTS",
+	Ports:    "PORTS",
+	IP:       "IP",
+	Network:  "NETWORK",
+	Command:  "COMMAND",
+	Created:  "CREATED",
+	Labels:   "LABELS",
+	header:   true,
+}
+
+// ListContainer print the containers by the options, only the running
+// containers are printed unless all is set or the status is filtered
+func ListContainer(opts *PsOptions) error {
+	filters, err := ParseFilters(opts.Filters)
+	if err != nil {
+		return err
+	}
+	containers, err := GetAllContainers()
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read the directory error %v", err)
+	}
+	// the newest container is the first
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].CreateAt.After(containers[j].CreateAt)
+	})
+	now := time.Now()
+	rows := []*psRow{}
+	for _, item := range containers {
+		RefreshContainerStatus(item)
+		if !opts.All && len(filters.Filters[filterStatus]) == 0 && item.Status != RUNING && item.Status != PAUSED && item.Status != RESTARTING {
+			continue
+		}
+		if !filters.Match(item) {
+			continue
+		}
+		rows = append(rows, newPsRow(item, now))
+	}
+	if opts.Quiet {
+		for _, row := range rows {
+			fmt.Println(row.ID)
+		}
+		return nil
+	}
+	return printPsRows(rows, opts.Format)
+}
+
+// ParseFilters parse the filters in the format of key=value
+func ParseFilters(filters []string) (*Filters, error) {
+	parsed, err := label.ParseFilters(filters, filterStatus, filterName, filterLabel, filterAncestor, filterNetwork)
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range parsed[filterStatus] {
+		status, err := parseStatusFilter(value)
+		if err != nil {
+			return nil, err
+		}
+		parsed[filterStatus][i] = status
+	}
+	return &Filters{parsed}, nil
+}
+
+// Match check whether the container matches all the filters
+func (f *Filters) Match(meta *ContainerMeta) bool {
+	return f.MatchAny(filterStatus, func(status string) bool { return meta.Status == status }) &&
+		f.MatchAny(filterName, func(name string) bool { return strings.Contains(meta.Name, name) }) &&
+		f.MatchAny(filterLabel, func(filter string) bool { return label.Match(meta.Labels, filter) }) &&
+		f.MatchAny(filterAncestor, func(image string) bool { return meta.Image == image }) &&
+		f.MatchAny(filterNetwork, func(network string) bool { return meta.Network == network })
+}
+
+// the status of the filter, running is the alias of runing
+func parseStatusFilter(status string) (string, error) {
+	switch status {
+	case "running", RUNING:
+		return RUNING, nil
+	case PAUSED, RESTARTING, STOP, EXIT:
+		return status, nil
+	}
+	return "", fmt.Errorf("invalid status %q, the status must be running, paused, restarting, stopped or exited", status)
+}
+
+func newPsRow(meta *ContainerMeta, now time.Time) *psRow {
+	row := &psRow{
+		ID:       ShortID(meta.ID),
+		Name:     meta.Name,
+		Image:    meta.Image,
+		PID:      "-",
+		Status:   formatStatus(meta),
+		Uptime:   "-",
+		Restarts: strconv.Itoa(meta.RestartCount),
+		Network:  meta.Network,
+		Command:  meta.Command,
+		Created:  meta.CreateAt.Format(time.DateTime),
+		labels:   meta.Labels,
+	}
+	if meta.Status == RUNING || meta.Status == PAUSED {
+		row.PID = strconv.Itoa(meta.PID)
+		startAt := meta.StartAt
+		if startAt.IsZero() {
+			startAt = meta.CreateAt
+		}
+		row.Uptime = humanDuration(now.Sub(startAt))
+	}
+	// e.g. 8080->80/tcp
+	ports := []string{}
+	for _, port := range strings.Fields(meta.Port) {
+		if host, target, ok := strings.Cut(port, ":"); ok {
+			ports = append(ports, fmt.Sprintf("%s->%s/tcp", host, target))
+		}
+	}
+	row.Ports = strings.Join(ports, ", ")
+	if ip, _, ok := strings.Cut(meta.IP, "/"); ok {
+		row.IP = ip
+	}
+	labels := []string{}
+	for key, value := range meta.Labels {
+		labels = append(labels, key+"="+value)
+	}
+	sort.Strings(labels)
+	row.Labels = strings.Join(labels, ",")
+	return row
+}
+
+func printPsRows(rows []*psRow, format string) error {
+	switch format {
+	case "", "table":
+		format = defaultPsFormat
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		for _, row := range rows {
+			if err := encoder.Encode(row); err != nil {
+				return fmt.Errorf("encode container error %v", err)
+			}
+		}
+		return nil
+	}
+	// the tab in the shell is written as \t
+	format = strings.ReplaceAll(format, `\t`, "\t")
+	table := strings.HasPrefix(format, "table ")
+	tmpl, err := ParseFormat(strings.TrimPrefix(format, "table "))
+	if err != nil {
+		return err
+	}
+	if !table {
+		for _, row := range rows {
+			if err := ExecuteFormat(os.Stdout, tmpl, row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
+	for _, row := range append([]*psRow{psHeader}, rows...) {
+		if err := ExecuteFormat(w, tmpl, row); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		zap.L().Sugar().Errorf("flush error %v", err)
+	}
+	return nil
+}
+
+// ShortID truncate the container id
+func ShortID(id string) string {
+	if len(id) > shortIDLength {
+		return id[:shortIDLength]
+	}
+	return id
+}
+
+// the duration for human, e.g. 5 minutes
+func humanDuration(d time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	switch {
+	case d < time.Minute:
+		return plural(int(d.Seconds()), "second")
+	case d < time.Hour:
+		return plural(int(d.Minutes()), "minute")
+	case d < 48*time.Hour:
+		return plural(int(d.Hours()), "hour")
+	}
+	return plural(int(d.Hours()/24), "day")
+}
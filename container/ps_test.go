@@ -0,0 +1,47 @@
+package container
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilters(t *testing.T) {
+	assert := assert.New(t)
+	meta := &ContainerMeta{
+		Name:    "web-1",
+		Status:  RUNING,
+		Image:   "busybox",
+		Network: "net0",
+		Labels:  map[string]string{"team": "infra"},
+	}
+	match := func(filters ...string) bool {
+		f, err := ParseFilters(filters)
+		assert.Nil(err)
+		return f.Match(meta)
+	}
+	assert.True(match())
+	assert.True(match("status=running", "name=web"))
+	assert.True(match("status=exited", "status=runing"))
+	assert.False(match("status=exited"))
+	assert.True(match("label=team", "label=team=infra"))
+	assert.False(match("label=team=web"))
+	assert.False(match("label=owner"))
+	assert.True(match("ancestor=busybox", "network=net0"))
+	assert.False(match("ancestor=busybox", "network=net1"))
+
+	for _, filter := range []string{"status", "status=", "status=up", "id=abc"} {
+		_, err := ParseFilters([]string{filter})
+		assert.NotNil(err, filter)
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("1 second", humanDuration(time.Second))
+	assert.Equal("59 seconds", humanDuration(59*time.Second))
+	assert.Equal("5 minutes", humanDuration(5*time.Minute+10*time.Second))
+	assert.Equal("47 hours", humanDuration(47*time.Hour))
+	assert.Equal("3 days", humanDuration(72*time.Hour))
+}
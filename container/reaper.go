@@ -0,0 +1,81 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// run the user command as the child of the init process. the init process
+// stays as pid 1, it forwards the signals to the child, reaps the orphaned
+// zombies and exits with the exit status of the child. only the child runs
+// as the user of the container
+func runAsInit(path string, args, env []string, cred *syscall.Credential) error {
+	// the signals must be caught before the child starts
+	sigs := make(chan os.Signal, 128)
+	signal.Notify(sigs)
+
+	cmd := &exec.Cmd{
+		Path:   path,
+		Args:   args,
+		Env:    env,
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+	if cred != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: cred}
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start the user command error %v", err)
+	}
+	child := cmd.Process.Pid
+	zap.L().Sugar().Infof("init forks the user command, pid %d", child)
+
+	for sig := range sigs {
+		switch sig {
+		case syscall.SIGCHLD:
+			if exitCode, exited := reapZombies(child); exited {
+				os.Exit(exitCode)
+			}
+		// the go runtime uses SIGURG to preempt goroutines
+		case syscall.SIGURG:
+		default:
+			if err := cmd.Process.Signal(sig); err != nil {
+				zap.L().Sugar().Warnf("forward signal %v error %v", sig, err)
+			}
+		}
+	}
+	return nil
+}
+
+// reap all the exited children, the signals may be merged so one SIGCHLD
+// can stand for several children. return the exit code if the user
+// command exited
+func reapZombies(child int) (int, bool) {
+	exitCode, exited := 0, false
+	for {
+		var status syscall.WaitStatus
+		pid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if pid <= 0 || err != nil {
+			return exitCode, exited
+		}
+		if pid != child {
+			continue
+		}
+		exited = true
+		if status.Signaled() {
+			// the shell convention, 128 + signal number
+			exitCode = 128 + int(status.Signal())
+		} else {
+			exitCode = status.ExitStatus()
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// the name is used as the directory of the container
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// GetContainer get the container by the full id, the name or the unique
+// prefix of the id, in that order
+func GetContainer(ref string) (*ContainerMeta, error) {
+	if ref == "" {
+		return nil, fmt.Errorf("the container name or id is empty")
+	}
+	containers, err := GetAllContainers()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read the directory error %v", err)
+	}
+	return resolveContainer(containers, ref)
+}
+
+func resolveContainer(containers []*ContainerMeta, ref string) (*ContainerMeta, error) {
+	for _, meta := range containers {
+		if meta.ID == ref {
+			return meta, nil
+		}
+	}
+	for _, meta := range containers {
+		if meta.Name == ref {
+			return meta, nil
+		}
+	}
+	matched := []*ContainerMeta{}
+	for _, meta := range containers {
+		if strings.HasPrefix(meta.ID, ref) {
+			matched = append(matched, meta)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return nil, fmt.Errorf("no such container: %s", ref)
+	case 1:
+		return matched[0], nil
+	}
+	names := []string{}
+	for _, meta := range matched {
+		names = append(names, meta.Name)
+	}
+	return nil, fmt.Errorf("the id prefix %s is ambiguous, it matches the containers %s", ref, strings.Join(names, ", "))
+}
+
+// ReserveName create the directory of the new container, the name is in use if
+// the directory exists. the directory is removed if the container fails to start
+func ReserveName(containerName string) error {
+	if !validName.MatchString(containerName) {
+		return fmt.Errorf("invalid container name %q, only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed", containerName)
+	}
+	dirPath := fmt.Sprintf(DefaultInfoPath, containerName)
+	if err := os.MkdirAll(filepath.Dir(filepath.Clean(dirPath)), 0755); err != nil {
+		return fmt.Errorf("mkdir dir %s error %v", dirPath, err)
+	}
+	if err := os.Mkdir(dirPath, 0755); err != nil {
+		if !os.IsExist(err) {
+			return fmt.Errorf("mkdir dir %s error %v", dirPath, err)
+		}
+		// the directory without config is the container being created
+		if meta, err := GetContainerByName(containerName); err == nil {
+			return fmt.Errorf("the container name %s is already in use by container %s", containerName, ShortID(meta.ID))
+		}
+		return fmt.Errorf("the container name %s is already in use", containerName)
+	}
+	return nil
+}
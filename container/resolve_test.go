@@ -0,0 +1,43 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveContainer(t *testing.T) {
+	assert := assert.New(t)
+	containers := []*ContainerMeta{
+		{ID: "abc123", Name: "web"},
+		{ID: "abd456", Name: "db"},
+		{ID: "fff789", Name: "abc123f"},
+	}
+	resolve := func(ref string) string {
+		meta, err := resolveContainer(containers, ref)
+		if err != nil {
+			return ""
+		}
+		return meta.Name
+	}
+	assert.Equal("web", resolve("abc123"))
+	assert.Equal("db", resolve("db"))
+	assert.Equal("web", resolve("abc"))
+	assert.Equal("abc123f", resolve("abc123f"))
+	assert.Equal("abc123f", resolve("fff"))
+	// the prefix matches two containers
+	_, err := resolveContainer(containers, "ab")
+	assert.ErrorContains(err, "ambiguous")
+	_, err = resolveContainer(containers, "xyz")
+	assert.ErrorContains(err, "no such container")
+}
+
+func TestValidName(t *testing.T) {
+	assert := assert.New(t)
+	for _, name := range []string{"web", "web-1", "a.b_c", "0abc"} {
+		assert.True(validName.MatchString(name), name)
+	}
+	for _, name := range []string{"", "-web", ".", "..", "../x", "a/b", "a b"} {
+		assert.False(validName.MatchString(name), name)
+	}
+}
@@ -0,0 +1,105 @@
+package container
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// restart policy
+	RestartNo            = "no"
+	RestartAlways        = "always"
+	RestartOnFailure     = "on-failure"
+	RestartUnlessStopped = "unless-stopped"
+
+	// the delay before restarting the container doubles every restart
+	// until the container has been running long enough
+	minRestartDelay   = 100 * time.Millisecond
+	maxRestartDelay   = time.Minute
+	resetRestartDelay = 10 * time.Second
+)
+
+// the policy of restarting the container after it exits
+type RestartPolicy struct {
+	Name string `json:"name"`
+	// the max number of restarts of on-failure, 0 means unlimited
+	MaximumRetryCount int `json:"maximum_retry_count,omitempty"`
+}
+
+// ParseRestartPolicy parse the restart policy, the format is
+// no, always, unless-stopped, on-failure or on-failure:max-retries
+func ParseRestartPolicy(policy string) (*RestartPolicy, error) {
+	name, retries, hasRetries := strings.Cut(policy, ":")
+	switch name {
+	case "", RestartNo:
+		name = RestartNo
+	case RestartAlways, RestartUnlessStopped, RestartOnFailure:
+	default:
+		return nil, fmt.Errorf("invalid restart policy %q", policy)
+	}
+	if !hasRetries {
+		return &RestartPolicy{Name: name}, nil
+	}
+	if name != RestartOnFailure {
+		return nil, fmt.Errorf("invalid restart policy %q, only on-failure supports the max retries", policy)
+	}
+	count, err := strconv.Atoi(retries)
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("invalid restart policy %q, the max retries must be a non-negative integer", policy)
+	}
+	return &RestartPolicy{Name: name, MaximumRetryCount: count}, nil
+}
+
+// IsNone check whether the container is never restarted
+func (p *RestartPolicy) IsNone() bool {
+	return p == nil || p.Name == RestartNo
+}
+
+// ShouldRestart check whether the exited container should be restarted by
+// the shim. the container stopped by user is never restarted, so always is
+// the same as unless-stopped until the host reboots
+func (p *RestartPolicy) ShouldRestart(meta *ContainerMeta) bool {
+	if p.IsNone() || meta.ManuallyStopped {
+		return false
+	}
+	if p.Name == RestartOnFailure {
+		return meta.ExitCode != 0 && (p.MaximumRetryCount == 0 || meta.RestartCount < p.MaximumRetryCount)
+	}
+	return true
+}
+
+// ShouldRestartOnBoot check whether the container should be started after the
+// host reboots, always starts the container stopped by user too
+func (p *RestartPolicy) ShouldRestartOnBoot(meta *ContainerMeta) bool {
+	if p.IsNone() {
+		return false
+	}
+	switch p.Name {
+	case RestartAlways:
+		return true
+	case RestartUnlessStopped:
+		return !meta.ManuallyStopped
+	}
+	return false
+}
+
+func (p *RestartPolicy) String() string {
+	if p.IsNone() {
+		return RestartNo
+	}
+	if p.MaximumRetryCount > 0 {
+		return fmt.Sprintf("%s:%d", p.Name, p.MaximumRetryCount)
+	}
+	return p.Name
+}
+
+// RestartDelay return the delay before the next restart, the delay is reset
+// if the container has been running for a while
+func RestartDelay(last time.Duration, running time.Duration) time.Duration {
+	if last == 0 || running >= resetRestartDelay {
+		return minRestartDelay
+	}
+	return min(last*2, maxRestartDelay)
+}
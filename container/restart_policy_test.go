@@ -0,0 +1,62 @@
+package container
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRestartPolicy(t *testing.T) {
+	assert := assert.New(t)
+	cases := map[string]RestartPolicy{
+		"":               {Name: RestartNo},
+		"no":             {Name: RestartNo},
+		"always":         {Name: RestartAlways},
+		"unless-stopped": {Name: RestartUnlessStopped},
+		"on-failure":     {Name: RestartOnFailure},
+		"on-failure:3":   {Name: RestartOnFailure, MaximumRetryCount: 3},
+	}
+	for policy, expected := range cases {
+		value, err := ParseRestartPolicy(policy)
+		assert.Nil(err, "parse %s should return nil", policy)
+		assert.Equal(expected, *value, "parse %s", policy)
+	}
+	for _, policy := range []string{"never", "always:3", "on-failure:", "on-failure:-1", "on-failure:x"} {
+		_, err := ParseRestartPolicy(policy)
+		assert.NotNil(err, "parse %s should return error", policy)
+	}
+}
+
+func TestShouldRestart(t *testing.T) {
+	assert := assert.New(t)
+	always := &RestartPolicy{Name: RestartAlways}
+	onFailure := &RestartPolicy{Name: RestartOnFailure, MaximumRetryCount: 2}
+
+	assert.False((*RestartPolicy)(nil).ShouldRestart(&ContainerMeta{Status: EXIT, ExitCode: 1}))
+	assert.True(always.ShouldRestart(&ContainerMeta{Status: EXIT}))
+	assert.False(always.ShouldRestart(&ContainerMeta{Status: STOP, ManuallyStopped: true}), "the stopped container isn't restarted")
+	assert.False(onFailure.ShouldRestart(&ContainerMeta{Status: EXIT}))
+	assert.True(onFailure.ShouldRestart(&ContainerMeta{Status: EXIT, ExitCode: 1, RestartCount: 1}))
+	assert.False(onFailure.ShouldRestart(&ContainerMeta{Status: EXIT, ExitCode: 1, RestartCount: 2}))
+}
+
+func TestShouldRestartOnBoot(t *testing.T) {
+	assert := assert.New(t)
+	stopped := &ContainerMeta{Status: STOP, ManuallyStopped: true}
+	exited := &ContainerMeta{Status: EXIT, ExitCode: 1}
+
+	assert.False((*RestartPolicy)(nil).ShouldRestartOnBoot(exited))
+	assert.True((&RestartPolicy{Name: RestartAlways}).ShouldRestartOnBoot(stopped), "always starts the stopped container after reboot")
+	assert.False((&RestartPolicy{Name: RestartUnlessStopped}).ShouldRestartOnBoot(stopped))
+	assert.True((&RestartPolicy{Name: RestartUnlessStopped}).ShouldRestartOnBoot(exited))
+	assert.False((&RestartPolicy{Name: RestartOnFailure}).ShouldRestartOnBoot(exited))
+}
+
+func TestRestartDelay(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(minRestartDelay, RestartDelay(0, 0))
+	assert.Equal(2*minRestartDelay, RestartDelay(minRestartDelay, time.Second))
+	assert.Equal(maxRestartDelay, RestartDelay(maxRestartDelay, time.Second))
+	assert.Equal(minRestartDelay, RestartDelay(maxRestartDelay, time.Minute))
+}
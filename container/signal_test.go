@@ -0,0 +1,29 @@
+package container
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSignal(t *testing.T) {
+	assert := assert.New(t)
+	cases := map[string]syscall.Signal{
+		"SIGTERM": syscall.SIGTERM,
+		"term":    syscall.SIGTERM,
+		"KILL":    syscall.SIGKILL,
+		"sigusr1": syscall.SIGUSR1,
+		"9":       syscall.SIGKILL,
+		"34":      syscall.Signal(34),
+	}
+	for signal, expected := range cases {
+		value, err := ParseSignal(signal)
+		assert.Nil(err, "parse %s should return nil", signal)
+		assert.Equal(expected, value, "parse %s", signal)
+	}
+	for _, signal := range []string{"", "SIG", "FOO", "0", "65", "-9"} {
+		_, err := ParseSignal(signal)
+		assert.NotNil(err, "parse %s should return error", signal)
+	}
+}
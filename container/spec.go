@@ -1,33 +1,123 @@
 package container
 
-import "time"
+import (
+	"mini-docker/cgroup/subsystems"
+	"mini-docker/store"
+	"syscall"
+	"time"
+)
 
 // container information
 type ContainerMeta struct {
+	store.Versioned
 	PID      int       `json:"pid"`
 	ID       string    `json:"id"`
 	Name     string    `json:"name"`
 	CreateAt time.Time `json:"create_at"`
 	Command  string    `json:"command"`
+	Args     []string  `json:"args"`
+	Env      []string  `json:"env,omitempty"`
 	Status   string    `json:"status"`
 	Volume   string    `json:"volume,omitempty"`
 	Image    string    `json:"image"`
 	Port     string    `json:"port,omitempty"`
+	Network  string    `json:"network,omitempty"`
 	IP       string    `json:"ip,omitempty"`
+	// the process waits for the container, the shim or the foreground cli
+	ShimPID int `json:"shim_pid,omitempty"`
+	// cgroup path relative to the hierarchy root
+	CgroupPath string `json:"cgroup_path,omitempty"`
+	// resource limits of the cgroup
+	Resource *subsystems.ResourceConfig `json:"resource,omitempty"`
+	// the cause of exit, -1 means the exit code is unknown
+	ExitCode  int       `json:"exit_code"`
+	OOMKilled bool      `json:"oom_killed"`
+	FinishAt  time.Time `json:"finish_at"`
+	// the last time the container was started, which is updated by restart
+	StartAt time.Time `json:"start_at"`
+	// the shim restarts the exited container by the policy
+	RestartPolicy *RestartPolicy `json:"restart_policy,omitempty"`
+	RestartCount  int            `json:"restart_count"`
+	// the signal sent by stop, and whether the container is stopped by user
+	StopSignal      string `json:"stop_signal,omitempty"`
+	ManuallyStopped bool   `json:"manually_stopped"`
+	// the init process of mini-docker runs as pid 1
+	Init bool `json:"init,omitempty"`
+	// the process settings of the user command
+	Workdir  string `json:"workdir,omitempty"`
+	User     string `json:"user,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	// the stdio of the attached container
+	TTY         bool `json:"tty,omitempty"`
+	Interactive bool `json:"interactive,omitempty"`
+	// the log driver and its options
+	LogDriver string            `json:"log_driver,omitempty"`
+	LogOpts   map[string]string `json:"log_opts,omitempty"`
+	// the user defined metadata, e.g. team=infra
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// the configuration sent to the init process through the pipe
+type InitConfig struct {
+	Args     []string `json:"args"`
+	Env      []string `json:"env"`
+	Cwd      string   `json:"cwd"`
+	User     string   `json:"user"`
+	Hostname string   `json:"hostname"`
+	Mounts   []Mount  `json:"mounts"`
+	// stay as pid 1 to reap zombies and forward signals
+	Init bool `json:"init"`
+}
+
+// the mount in the container, which is mounted after pivot_root
+type Mount struct {
+	Source string  `json:"source"`
+	Target string  `json:"target"`
+	Type   string  `json:"type"`
+	Flags  uintptr `json:"flags"`
+	Data   string  `json:"data"`
 }
 
 const (
 	// container status
-	RUNING = "runing"
-	STOP   = "stopped"
-	EXIT   = "exited"
+	RUNING     = "runing"
+	PAUSED     = "paused"
+	RESTARTING = "restarting"
+	STOP       = "stopped"
+	EXIT       = "exited"
+
+	// the exit code of the process killed by SIGKILL
+	ExitCodeKilled = 137
+	// the default time to wait for the container to exit after the stop signal
+	DefaultStopTimeout = 10 * time.Second
+	// the time to wait for the process to exit after SIGKILL
+	stopWaitTimeout = 5 * time.Second
 
 	// constant
 	ConfigName      = "config.json"
 	ContainerLog    = "container.log"
+	AttachSocket    = "attach.sock"
 	DefaultInfoPath = "/var/run/mini-docker/container/%s/"
 
 	// environment
 	ENV_EXEC_PID = "mini_docker_pid"
-	ENV_EXEC_CMD = "mini_docker_cmd"
 )
+
+// the mounts of every container
+func DefaultMounts() []Mount {
+	return []Mount{
+		{
+			Source: "proc",
+			Target: "/proc",
+			Type:   "proc",
+			Flags:  syscall.MS_NOEXEC | syscall.MS_NOSUID | syscall.MS_NODEV,
+		},
+		{
+			Source: "tmpfs",
+			Target: "/dev",
+			Type:   "tmpfs",
+			Flags:  syscall.MS_STRICTATIME | syscall.MS_NOSUID,
+			Data:   "mode=755",
+		},
+	}
+}
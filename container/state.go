@@ -0,0 +1,135 @@
+package container
+
+import (
+	"fmt"
+	"mini-docker/cgroup"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RecordExit record the cause of exit of the container process
+func RecordExit(containerName string, exitCode int, oomKilled bool) error {
+	_, err := updateContainerMeta(containerName, func(meta *ContainerMeta) error {
+		markExited(meta, exitCode, oomKilled)
+		return nil
+	})
+	return err
+}
+
+func markExited(meta *ContainerMeta, exitCode int, oomKilled bool) {
+	if meta.ManuallyStopped {
+		meta.Status = STOP
+	} else {
+		meta.Status = EXIT
+	}
+	meta.PID = -1
+	meta.ExitCode = exitCode
+	meta.OOMKilled = oomKilled
+	meta.FinishAt = time.Now()
+}
+
+// MarkExited mark the container exited whose process is gone without anyone
+// recording it, e.g. the host reboots. the container is unchanged if it's
+// started again meanwhile. the shim pid is cleared if the shim is known gone,
+// so the container is started without waiting for the old shim
+func MarkExited(meta *ContainerMeta, shimGone bool) error {
+	updated, err := updateContainerMeta(meta.Name, func(latest *ContainerMeta) error {
+		if latest.PID == meta.PID && latest.Status == meta.Status {
+			markExited(latest, -1, false)
+			if shimGone {
+				latest.ShimPID = 0
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*meta = *updated
+	return nil
+}
+
+// MarkRestarting mark the exited container restarting, the shim restarts it
+// after the delay unless the container is stopped by user
+func MarkRestarting(meta *ContainerMeta) error {
+	updated, err := updateContainerMeta(meta.Name, func(latest *ContainerMeta) error {
+		latest.Status = RESTARTING
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*meta = *updated
+	return nil
+}
+
+// RefreshContainerStatus mark the container exited if its process is gone.
+// the container process may exit without anyone recording it, e.g. killed by
+// the oom killer after run -d. the exit code is unknown in this case except
+// the oom kill, which always uses SIGKILL
+func RefreshContainerStatus(meta *ContainerMeta) {
+	if (meta.Status != RUNING && meta.Status != PAUSED) || ProcessExists(meta.PID) {
+		return
+	}
+	oomKilled := meta.CgroupPath != "" && cgroup.NewCgroupManager(meta.CgroupPath).OOMKilled()
+	exitCode := -1
+	if oomKilled {
+		exitCode = ExitCodeKilled
+	}
+	updated, err := updateContainerMeta(meta.Name, func(latest *ContainerMeta) error {
+		// the exit is recorded by the shim, or the container is restarted
+		if latest.PID == meta.PID && (latest.Status == RUNING || latest.Status == PAUSED) {
+			markExited(latest, exitCode, oomKilled)
+		}
+		return nil
+	})
+	if err != nil {
+		zap.L().Sugar().Warnf("record the exit of container %s error %v", meta.Name, err)
+		markExited(meta, exitCode, oomKilled)
+		return
+	}
+	*meta = *updated
+}
+
+// ProcessExists check whether the process is alive, the zombie process has
+// exited, it only waits for its parent
+func ProcessExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	// the format is: pid (comm) state ..., comm may contain spaces
+	fields := strings.Fields(string(stat[strings.LastIndex(string(stat), ")")+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+// WaitProcessExit wait until the process exits, return false if it's still
+// alive after the timeout
+func WaitProcessExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for ProcessExists(pid) {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return true
+}
+
+// the status shown in ps, e.g. exited (137) OOMKilled
+func formatStatus(meta *ContainerMeta) string {
+	if meta.Status != EXIT {
+		return meta.Status
+	}
+	status := fmt.Sprintf("%s (%d)", meta.Status, meta.ExitCode)
+	if meta.OOMKilled {
+		status += " OOMKilled"
+	}
+	return status
+}
@@ -0,0 +1,78 @@
+package container
+
+import (
+	"mini-docker/cgroup/subsystems"
+	"mini-docker/store"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateBetweenRestarts(t *testing.T) {
+	assert := assert.New(t)
+	root := t.TempDir()
+	old := metaStore
+	metaStore = store.New(root, "%s/"+ConfigName, metaSchemaVersion, nil)
+	defer func() { metaStore = old }()
+	os.Mkdir(filepath.Join(root, "web"), 0755)
+
+	policy := &RestartPolicy{Name: RestartAlways}
+	assert.Nil(RecordContainer(&ContainerMeta{
+		ID:            "abc",
+		Name:          "web",
+		Resource:      &subsystems.ResourceConfig{MemoryLimit: "100m"},
+		RestartPolicy: policy,
+	}))
+	for _, limit := range []string{"200m", "300m"} {
+		assert.Nil(RecordExit("web", 1, false))
+		meta, err := GetContainerByName("web")
+		assert.Nil(err)
+		assert.Nil(MarkRestarting(meta))
+		// the container has no cgroup while it's restarting
+		assert.Nil(UpdateContainer("web", &subsystems.ResourceConfig{MemoryLimit: limit}))
+
+		// the shim restarts the container with the latest limits
+		meta, err = GetContainerByName("web")
+		assert.Nil(err)
+		assert.Equal(limit, meta.Resource.MemoryLimit)
+		assert.Nil(RecordContainer(&ContainerMeta{
+			ID:            "abc",
+			Name:          "web",
+			Resource:      meta.Resource,
+			RestartPolicy: meta.RestartPolicy,
+		}))
+		meta, err = GetContainerByName("web")
+		assert.Nil(err)
+		assert.Equal(RUNING, meta.Status)
+		assert.Equal(limit, meta.Resource.MemoryLimit)
+	}
+}
+
+func TestRemoveRunningContainer(t *testing.T) {
+	assert := assert.New(t)
+	root := t.TempDir()
+	old := metaStore
+	metaStore = store.New(root, "%s/"+ConfigName, metaSchemaVersion, nil)
+	defer func() { metaStore = old }()
+	os.Mkdir(filepath.Join(root, "web"), 0755)
+
+	assert.Nil(RecordContainer(&ContainerMeta{ID: "abc", Name: "web", Status: RUNING, PID: os.Getpid(), IP: "10.0.0.2/24"}))
+	_, err := GetStoppedContainer("web")
+	assert.NotNil(err)
+	assert.NotNil(RemoveContainer("web"))
+	meta, err := GetContainerByName("web")
+	assert.Nil(err)
+	assert.Equal(RUNING, meta.Status)
+
+	// the container whose process is gone is removable
+	cmd := exec.Command("true")
+	assert.Nil(cmd.Run())
+	os.Mkdir(filepath.Join(root, "dead"), 0755)
+	assert.Nil(RecordContainer(&ContainerMeta{ID: "def", Name: "dead", Status: RUNING, PID: cmd.Process.Pid}))
+	meta, err = GetStoppedContainer("dead")
+	assert.Nil(err)
+	assert.Equal(EXIT, meta.Status)
+}
@@ -0,0 +1,250 @@
+package container
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"mini-docker/cgroup"
+	"mini-docker/cgroup/subsystems"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// the refresh interval of stats
+const statsInterval = time.Second
+
+// resource usage of a container
+type ContainerStats struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryUsage   uint64  `json:"memory_usage"`
+	MemoryPeak    uint64  `json:"memory_peak"`
+	MemoryLimit   uint64  `json:"memory_limit"`
+	MemoryPercent float64 `json:"memory_percent"`
+	NetRx         uint64  `json:"net_rx"`
+	NetTx         uint64  `json:"net_tx"`
+	BlockRead     uint64  `json:"block_read"`
+	BlockWrite    uint64  `json:"block_write"`
+	Pids          uint64  `json:"pids"`
+}
+
+// the previous sample, which is used to calculate the cpu percent
+type statsSample struct {
+	cpuUsage uint64
+	at       time.Time
+}
+
+// ContainerStatistics print the resource usage of the containers, all the running
+// containers are used if containerNames is empty. the output is refreshed every
+// second unless noStream is set, format is table or json
+func ContainerStatistics(containerNames []string, noStream bool, format string) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unsupported format %s", format)
+	}
+	// the named containers must be running at first
+	metas, err := getStatsContainers(containerNames, true)
+	if err != nil {
+		return err
+	}
+
+	samples := map[string]statsSample{}
+	// the cpu percent needs two samples
+	collectStats(metas, samples)
+	for {
+		time.Sleep(statsInterval)
+		// the containers are read again, they may exit or restart with new pids
+		metas, err = getStatsContainers(containerNames, false)
+		if err != nil {
+			return err
+		}
+		stats := collectStats(metas, samples)
+		if format == "json" {
+			printStatsJSON(stats)
+		} else {
+			if !noStream {
+				// clear the screen and move the cursor to the top left
+				fmt.Print("\033[2J\033[H")
+			}
+			printStatsTable(stats)
+		}
+		if noStream {
+			return nil
+		}
+	}
+}
+
+// get the running containers, the named containers which aren't running are
+// skipped unless strict is set
+func getStatsContainers(containerNames []string, strict bool) ([]*ContainerMeta, error) {
+	metas := []*ContainerMeta{}
+	if len(containerNames) == 0 {
+		containers, err := GetAllContainers()
+		if err != nil {
+			return nil, err
+		}
+		for _, meta := range containers {
+			RefreshContainerStatus(meta)
+			if meta.Status == RUNING || meta.Status == PAUSED {
+				metas = append(metas, meta)
+			}
+		}
+		return metas, nil
+	}
+	for _, containerName := range containerNames {
+		meta, err := GetContainerByName(containerName)
+		if err != nil {
+			if !strict && os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("get container %s error %v", containerName, err)
+		}
+		RefreshContainerStatus(meta)
+		if meta.Status != RUNING && meta.Status != PAUSED {
+			if !strict {
+				continue
+			}
+			return nil, fmt.Errorf("the container %s isn't running", containerName)
+		}
+		metas = append(metas, meta)
+	}
+	return metas, nil
+}
+
+func collectStats(metas []*ContainerMeta, samples map[string]statsSample) []*ContainerStats {
+	stats := []*ContainerStats{}
+	memTotal := getMemTotal()
+	for _, meta := range metas {
+		item := &ContainerStats{ID: meta.ID, Name: meta.Name}
+		if meta.CgroupPath != "" {
+			usage, _ := cgroup.NewCgroupManager(meta.CgroupPath).Stats()
+			fillCgroupStats(item, usage, memTotal)
+
+			now := time.Now()
+			if prev, ok := samples[meta.ID]; ok && usage.CpuUsage >= prev.cpuUsage {
+				// 100% means a whole cpu
+				item.CPUPercent = float64(usage.CpuUsage-prev.cpuUsage) / float64(now.Sub(prev.at).Nanoseconds()) * 100
+			}
+			samples[meta.ID] = statsSample{cpuUsage: usage.CpuUsage, at: now}
+		}
+		rx, tx, err := getNetworkStats(meta.PID)
+		if err != nil {
+			zap.L().Sugar().Warnf("get container %s network stats error %v", meta.Name, err)
+		}
+		item.NetRx, item.NetTx = rx, tx
+		stats = append(stats, item)
+	}
+	return stats
+}
+
+func fillCgroupStats(item *ContainerStats, usage *subsystems.Stats, memTotal uint64) {
+	item.MemoryUsage = usage.MemoryUsage
+	item.MemoryPeak = usage.MemoryPeak
+	item.MemoryLimit = usage.MemoryLimit
+	// the container without memory limit can use all the memory of the host
+	if item.MemoryLimit == 0 || (memTotal != 0 && item.MemoryLimit > memTotal) {
+		item.MemoryLimit = memTotal
+	}
+	if item.MemoryLimit != 0 {
+		item.MemoryPercent = float64(item.MemoryUsage) / float64(item.MemoryLimit) * 100
+	}
+	item.BlockRead = usage.BlockRead
+	item.BlockWrite = usage.BlockWrite
+	item.Pids = usage.PidsCurrent
+}
+
+// read the counters of the container veth from /proc/{pid}/net/dev,
+// the 1st column is the received bytes and the 9th is the transmitted bytes
+func getNetworkStats(pid int) (uint64, uint64, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/net/dev", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	var rx, tx uint64
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		device, counters, ok := strings.Cut(scan.Text(), ":")
+		if !ok || strings.TrimSpace(device) == "lo" {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			continue
+		}
+		r, _ := strconv.ParseUint(fields[0], 10, 64)
+		t, _ := strconv.ParseUint(fields[8], 10, 64)
+		rx, tx = rx+r, tx+t
+	}
+	return rx, tx, scan.Err()
+}
+
+// total memory of the host in bytes
+func getMemTotal() uint64 {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		// MemTotal:       16307580 kB
+		fields := strings.Fields(scan.Text())
+		if len(fields) == 3 && fields[0] == "MemTotal:" {
+			total, _ := strconv.ParseUint(fields[1], 10, 64)
+			return total * 1024
+		}
+	}
+	return 0
+}
+
+func printStatsJSON(stats []*ContainerStats) {
+	encoder := json.NewEncoder(os.Stdout)
+	for _, item := range stats {
+		if err := encoder.Encode(item); err != nil {
+			zap.L().Sugar().Errorf("encode container stats error %v", err)
+		}
+	}
+}
+
+func printStatsTable(stats []*ContainerStats) {
+	w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
+	fmt.Fprint(w, "ID\tNAME\tCPU %\tMEM USAGE / LIMIT\tMEM %\tMEM PEAK\tNET I/O\tBLOCK I/O\tPIDS\n")
+	for _, item := range stats {
+		fmt.Fprintf(w, "%s\t%s\t%.2f%%\t%s / %s\t%.2f%%\t%s\t%s / %s\t%s / %s\t%d\n",
+			ShortID(item.ID),
+			item.Name,
+			item.CPUPercent,
+			formatBytes(item.MemoryUsage), formatBytes(item.MemoryLimit),
+			item.MemoryPercent,
+			formatBytes(item.MemoryPeak),
+			formatBytes(item.NetRx), formatBytes(item.NetTx),
+			formatBytes(item.BlockRead), formatBytes(item.BlockWrite),
+			item.Pids,
+		)
+	}
+	if err := w.Flush(); err != nil {
+		zap.L().Sugar().Errorf("flush error %v", err)
+	}
+}
+
+// format bytes in binary units, e.g. 1.5MiB
+func formatBytes(size uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value, i := float64(size), 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d%s", size, units[i])
+	}
+	return fmt.Sprintf("%.2f%s", value, units[i])
+}
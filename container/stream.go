@@ -0,0 +1,85 @@
+package container
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// the streams between the shim and the attached client. every frame has a
+// 5 bytes header, the stream and the big-endian length of the payload
+const (
+	StreamStdin byte = iota
+	StreamStdout
+	StreamStderr
+	// the payload is the rows and the columns of the terminal
+	StreamResize
+	// the payload is the exit code of the container
+	StreamExit
+
+	frameHeaderSize = 5
+	// the max payload of a frame
+	maxFrameSize = 1 << 20
+)
+
+// WriteFrame write the payload as a frame of the stream, the empty stdin frame
+// means EOF of stdin
+func WriteFrame(w io.Writer, stream byte, payload []byte) error {
+	frame := make([]byte, frameHeaderSize+len(payload))
+	frame[0] = stream
+	binary.BigEndian.PutUint32(frame[1:frameHeaderSize], uint32(len(payload)))
+	copy(frame[frameHeaderSize:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReadFrame read a frame, return the stream and the payload
+func ReadFrame(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, frameHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxFrameSize {
+		return 0, nil, fmt.Errorf("the frame is too large, size %d", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+// EncodeWinsize encode the window size as the payload of the resize frame
+func EncodeWinsize(ws *Winsize) []byte {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint16(payload[0:2], ws.Row)
+	binary.BigEndian.PutUint16(payload[2:4], ws.Col)
+	return payload
+}
+
+// DecodeWinsize decode the payload of the resize frame
+func DecodeWinsize(payload []byte) (*Winsize, error) {
+	if len(payload) != 4 {
+		return nil, fmt.Errorf("invalid window size")
+	}
+	return &Winsize{
+		Row: binary.BigEndian.Uint16(payload[0:2]),
+		Col: binary.BigEndian.Uint16(payload[2:4]),
+	}, nil
+}
+
+// EncodeExitCode encode the exit code as the payload of the exit frame
+func EncodeExitCode(exitCode int) []byte {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint32(payload, uint32(int32(exitCode)))
+	return payload
+}
+
+// DecodeExitCode decode the payload of the exit frame
+func DecodeExitCode(payload []byte) (int, error) {
+	if len(payload) != 4 {
+		return 0, fmt.Errorf("invalid exit code")
+	}
+	return int(int32(binary.BigEndian.Uint32(payload))), nil
+}
@@ -0,0 +1,215 @@
+package container
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"go.uber.org/zap"
+)
+
+// the time to wait for the rest output of the pty after the process exits
+const consoleDrainTimeout = time.Second
+
+// Winsize the window size of the terminal, reference: struct winsize of ioctl_tty(2)
+type Winsize struct {
+	Row    uint16
+	Col    uint16
+	Xpixel uint16
+	Ypixel uint16
+}
+
+// Console connect the terminal of the host to the pty of the container
+type Console struct {
+	master *os.File
+	slave  *os.File
+	// the state of the host terminal before it's set to raw mode
+	state *syscall.Termios
+	sigs  chan os.Signal
+	done  chan struct{}
+}
+
+// NewConsole allocate a pty pair, the slave is the terminal of the container
+func NewConsole() (*Console, error) {
+	master, slave, err := OpenPty()
+	if err != nil {
+		return nil, fmt.Errorf("allocate pty error %v", err)
+	}
+	return &Console{
+		master: master,
+		slave:  slave,
+		sigs:   make(chan os.Signal, 1),
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Attach make the slave the stdio and the controlling terminal of the command
+func (c *Console) Attach(cmd *exec.Cmd) {
+	AttachPty(cmd, c.slave)
+}
+
+// Start copy between the host and the pty after the command started. the host
+// terminal is set to raw mode, so the control characters are handled by the pty
+func (c *Console) Start(stdin bool) error {
+	// the pty reports EOF only after all the slaves are closed
+	c.slave.Close()
+	if IsTerminal(os.Stdin.Fd()) {
+		state, err := MakeRaw(os.Stdin.Fd())
+		if err != nil {
+			return fmt.Errorf("set the terminal to raw mode error %v", err)
+		}
+		c.state = state
+		c.resize()
+		signal.Notify(c.sigs, syscall.SIGWINCH)
+		go func() {
+			for range c.sigs {
+				c.resize()
+			}
+		}()
+	}
+	if stdin {
+		go io.Copy(c.master, os.Stdin)
+	}
+	go func() {
+		// the read returns EIO after the slave is closed
+		io.Copy(os.Stdout, c.master)
+		close(c.done)
+	}()
+	return nil
+}
+
+// Close wait for the rest output and restore the host terminal
+func (c *Console) Close() {
+	select {
+	case <-c.done:
+	case <-time.After(consoleDrainTimeout):
+	}
+	signal.Stop(c.sigs)
+	close(c.sigs)
+	if c.state != nil {
+		if err := RestoreTerminal(os.Stdin.Fd(), c.state); err != nil {
+			zap.L().Sugar().Warnf("restore the terminal error %v", err)
+		}
+	}
+	c.master.Close()
+}
+
+// propagate the window size of the host terminal to the pty
+func (c *Console) resize() {
+	ws, err := GetWinsize(os.Stdin.Fd())
+	if err != nil {
+		zap.L().Sugar().Warnf("get window size error %v", err)
+		return
+	}
+	if err := SetWinsize(c.master.Fd(), ws); err != nil {
+		zap.L().Sugar().Warnf("set window size error %v", err)
+	}
+}
+
+// AttachPty make the slave the stdio and the controlling terminal of the command
+func AttachPty(cmd *exec.Cmd, slave *os.File) {
+	cmd.Stdin = slave
+	cmd.Stdout = slave
+	cmd.Stderr = slave
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	// the command leads a new session, the ctty is the fd in the child
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0
+}
+
+// OpenPty open a pty pair by /dev/ptmx, reference: pty(7)
+func OpenPty() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	// unlock the slave
+	var unlock int32
+	if err := ioctl(master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("unlock pty error %v", err)
+	}
+	var ptn uint32
+	if err := ioctl(master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&ptn))); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("get pty number error %v", err)
+	}
+	slavePath := fmt.Sprintf("/dev/pts/%d", ptn)
+	slave, err := os.OpenFile(slavePath, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return master, slave, nil
+}
+
+func IsTerminal(fd uintptr) bool {
+	_, err := getTermios(fd)
+	return err == nil
+}
+
+// MakeRaw set the terminal to raw mode and return the previous state,
+// reference: cfmakeraw of termios(3)
+func MakeRaw(fd uintptr) (*syscall.Termios, error) {
+	state, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+	raw := *state
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+		syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := setTermios(fd, &raw); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func getTermios(fd uintptr) (*syscall.Termios, error) {
+	termios := &syscall.Termios{}
+	if err := ioctl(fd, syscall.TCGETS, uintptr(unsafe.Pointer(termios))); err != nil {
+		return nil, err
+	}
+	return termios, nil
+}
+
+// RestoreTerminal restore the state returned by MakeRaw
+func RestoreTerminal(fd uintptr, state *syscall.Termios) error {
+	return setTermios(fd, state)
+}
+
+func setTermios(fd uintptr, termios *syscall.Termios) error {
+	return ioctl(fd, syscall.TCSETS, uintptr(unsafe.Pointer(termios)))
+}
+
+func GetWinsize(fd uintptr) (*Winsize, error) {
+	ws := &Winsize{}
+	if err := ioctl(fd, syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(ws))); err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+func SetWinsize(fd uintptr, ws *Winsize) error {
+	return ioctl(fd, syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(ws)))
+}
+
+func ioctl(fd, request, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	passwdPath = "/etc/passwd"
+	groupPath  = "/etc/group"
+)
+
+// resolve the user of the container by /etc/passwd and /etc/group of the
+// container, the format is user[:group], both can be a name or an id
+func lookupUser(user string) (*syscall.Credential, error) {
+	userName, groupName, hasGroup := strings.Cut(user, ":")
+	passwd, err := readOptionalFile(passwdPath)
+	if err != nil {
+		return nil, err
+	}
+	uid, gid, err := parsePasswd(bytes.NewReader(passwd), userName)
+	if err != nil {
+		return nil, err
+	}
+	if hasGroup {
+		group, err := readOptionalFile(groupPath)
+		if err != nil {
+			return nil, err
+		}
+		if gid, err = parseGroup(bytes.NewReader(group), groupName); err != nil {
+			return nil, err
+		}
+	}
+	return &syscall.Credential{Uid: uid, Gid: gid, Groups: []uint32{}}, nil
+}
+
+// the image may not have the file
+func readOptionalFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// find the uid and gid of the user in the passwd file, the format of a line is
+// name:password:uid:gid:gecos:home:shell. the numeric user which isn't in the
+// file belongs to the root group
+func parsePasswd(r io.Reader, user string) (uint32, uint32, error) {
+	id, idErr := parseID(user)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 4 || (fields[0] != user && fields[2] != user) {
+			continue
+		}
+		uid, err := parseID(fields[2])
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid uid of user %s", fields[0])
+		}
+		gid, err := parseID(fields[3])
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid gid of user %s", fields[0])
+		}
+		return uid, gid, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+	if idErr != nil {
+		return 0, 0, fmt.Errorf("unable to find user %s", user)
+	}
+	return id, 0, nil
+}
+
+// find the gid of the group in the group file, the format of a line is
+// name:password:gid:members
+func parseGroup(r io.Reader, group string) (uint32, error) {
+	id, idErr := parseID(group)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 3 || (fields[0] != group && fields[2] != group) {
+			continue
+		}
+		gid, err := parseID(fields[2])
+		if err != nil {
+			return 0, fmt.Errorf("invalid gid of group %s", fields[0])
+		}
+		return gid, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	if idErr != nil {
+		return 0, fmt.Errorf("unable to find group %s", group)
+	}
+	return id, nil
+}
+
+func parseID(id string) (uint32, error) {
+	value, err := strconv.ParseUint(id, 10, 32)
+	return uint32(value), err
+}
+
+// switch the user of the current process, the groups must be set before
+// the uid because an unprivileged user can't change them
+func setUser(cred *syscall.Credential) error {
+	groups := make([]int, len(cred.Groups))
+	for i, group := range cred.Groups {
+		groups[i] = int(group)
+	}
+	if err := syscall.Setgroups(groups); err != nil {
+		return fmt.Errorf("setgroups error %v", err)
+	}
+	if err := syscall.Setgid(int(cred.Gid)); err != nil {
+		return fmt.Errorf("setgid error %v", err)
+	}
+	if err := syscall.Setuid(int(cred.Uid)); err != nil {
+		return fmt.Errorf("setuid error %v", err)
+	}
+	return nil
+}
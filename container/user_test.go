@@ -0,0 +1,46 @@
+package container
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testPasswd = "root:x:0:0:root:/root:/bin/sh\nnobody:x:65534:65534:nobody:/nonexistent:/bin/false\n"
+	testGroup  = "root:x:0:\nwheel:x:10:root\nnogroup:x:65534:\n"
+)
+
+func TestParsePasswd(t *testing.T) {
+	assert := assert.New(t)
+	cases := map[string][2]uint32{
+		"root":   {0, 0},
+		"nobody": {65534, 65534},
+		"65534":  {65534, 65534},
+		"1000":   {1000, 0},
+	}
+	for user, expected := range cases {
+		uid, gid, err := parsePasswd(strings.NewReader(testPasswd), user)
+		assert.Nil(err, "parse %s should return nil", user)
+		assert.Equal(expected, [2]uint32{uid, gid}, "parse %s", user)
+	}
+	_, _, err := parsePasswd(strings.NewReader(testPasswd), "admin")
+	assert.NotNil(err, "parse unknown user should return error")
+}
+
+func TestParseGroup(t *testing.T) {
+	assert := assert.New(t)
+	cases := map[string]uint32{
+		"wheel": 10,
+		"10":    10,
+		"100":   100,
+	}
+	for group, expected := range cases {
+		gid, err := parseGroup(strings.NewReader(testGroup), group)
+		assert.Nil(err, "parse %s should return nil", group)
+		assert.Equal(expected, gid, "parse %s", group)
+	}
+	_, err := parseGroup(strings.NewReader(testGroup), "staff")
+	assert.NotNil(err, "parse unknown group should return error")
+}
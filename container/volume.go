@@ -6,25 +6,29 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"syscall"
 
 	"go.uber.org/zap"
 )
 
 // overlayfs
 // lowerdir + upperdir + workdir + mergedir
-func NewWorkSpace(imageName, containerName string, volumePath []string) error {
+// the overlayfs of the existing container is reused, the new container
+// mustn't inherit the left upper dir of a removed container with the same name
+func NewWorkSpace(imageName, containerName string, volumePath []string, existing bool) error {
 	if err := createOverlayfsLower(imageName); err != nil {
 		zap.L().Sugar().Errorf("create overlayfs lower error %v", err)
 		return ErrCreateWorkSpace
 	}
 	zap.L().Info("create overlayfs lower dir successful")
-	if err := createOverlayfsDirs(containerName); err != nil {
+	if err := createOverlayfsDirs(containerName, existing); err != nil {
 		zap.L().Sugar().Errorf("create overlayfs uppper or work error %v", err)
 		return ErrCreateWorkSpace
 	}
 	zap.L().Sugar().Info("create overlayfs upper and work dirs successful")
-	if err := mountOverlayfs(imageName, containerName); err != nil {
+	if err := mountOverlayfs(imageName, containerName, existing); err != nil {
 		zap.L().Sugar().Errorf("mount overlayfs error %v", err)
 		return ErrCreateWorkSpace
 	}
@@ -73,24 +77,25 @@ func createOverlayfsLower(imageName string) error {
 	return nil
 }
 
-// create overlayfs upper and work
-func createOverlayfsDirs(containerName string) error {
+// create overlayfs upper and work, the upper of the existing container is
+// reused
+func createOverlayfsDirs(containerName string, existing bool) error {
 	containerDir := filepath.Join(config.ContainerPath, containerName)
-	if err := os.Mkdir(containerDir, 0777); err != nil {
+	if err := os.Mkdir(containerDir, 0777); err != nil && !(existing && os.IsExist(err)) {
 		return fmt.Errorf("mkdir %s failed, error is %v", containerDir, err)
 	}
 	diff, work := filepath.Join(containerDir, "diff"), filepath.Join(containerDir, "work")
-	if err := os.Mkdir(diff, 0777); err != nil {
+	if err := os.Mkdir(diff, 0777); err != nil && !(existing && os.IsExist(err)) {
 		return fmt.Errorf("mkdir %s failed, error is %v", diff, err)
 	}
-	if err := os.Mkdir(work, 0777); err != nil {
+	if err := os.Mkdir(work, 0777); err != nil && !(existing && os.IsExist(err)) {
 		return fmt.Errorf("mkdir %s failed, error is %v", work, err)
 	}
 	return nil
 }
 
 // mount overlayfs
-func mountOverlayfs(imageName, containerName string) error {
+func mountOverlayfs(imageName, containerName string, existing bool) error {
 	imageUrl := filepath.Join(config.ImagePath, imageName)
 	containerUrl := filepath.Join(config.ContainerPath, containerName)
 
@@ -98,9 +103,13 @@ func mountOverlayfs(imageName, containerName string) error {
 	upper := filepath.Join(containerUrl, "diff")
 	work := filepath.Join(containerUrl, "work")
 
-	if err := os.Mkdir(mnt, 0777); err != nil {
+	if err := os.Mkdir(mnt, 0777); err != nil && !(existing && os.IsExist(err)) {
 		return fmt.Errorf("mkdir %s failed, error is %v", mnt, err)
 	}
+	// the overlayfs of the exited container is kept until it's removed
+	if existing && isMountPoint(mnt) {
+		return nil
+	}
 
 	dirs := "lowerdir=" + imageUrl + ",upperdir=" + upper + ",workdir=" + work
 	// mount
@@ -187,6 +196,9 @@ func mountVolume(containerName string, mappingVolumePath []string) error {
 	if err := os.Mkdir(containerPath, 0777); err != nil && !os.IsExist(err) {
 		return fmt.Errorf("mkdir dir %s error, error is %v", containerPath, err)
 	}
+	if isMountPoint(containerPath) {
+		return nil
+	}
 
 	// mount
 	cmd := exec.Command("mount", "--bind", hostPath, containerPath)
@@ -213,3 +225,55 @@ func fileExists(path string) (bool, error) {
 		return false, err
 	}
 }
+
+// check whether the path is a mount point by /proc/self/mountinfo,
+// the 5th field is the mount point
+func isMountPoint(path string) bool {
+	mountInfo, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(mountInfo), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 4 && fields[4] == filepath.Clean(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveWorkSpaceDir umount all the mounts under the directory of the
+// container, e.g. the volumes and the overlayfs, then remove the directory.
+// the directory is kept if any mount is left, the volume data mustn't be removed
+func RemoveWorkSpaceDir(containerName string) error {
+	dir := filepath.Join(config.ContainerPath, containerName)
+	mounts := mountPointsUnder(dir)
+	// the nested mounts are umounted first
+	sort.Sort(sort.Reverse(sort.StringSlice(mounts)))
+	for _, mnt := range mounts {
+		if err := syscall.Unmount(mnt, 0); err != nil {
+			return fmt.Errorf("umount %s error %v", mnt, err)
+		}
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("remove dir %s error %v", dir, err)
+	}
+	return nil
+}
+
+// the mount points under the directory, including itself
+func mountPointsUnder(dir string) []string {
+	mountInfo, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return nil
+	}
+	dir = filepath.Clean(dir)
+	mounts := []string{}
+	for _, line := range strings.Split(string(mountInfo), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 4 && (fields[4] == dir || strings.HasPrefix(fields[4], dir+"/")) {
+			mounts = append(mounts, fields[4])
+		}
+	}
+	return mounts
+}
@@ -0,0 +1,52 @@
+package label
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Filters the key=value filters of the list and prune commands, e.g.
+// label=team=infra. the values of the same key are or-ed, and the different
+// keys are and-ed
+type Filters map[string][]string
+
+// ParseFilters parse the filters in the format of key=value, the key must be
+// one of keys
+func ParseFilters(filters []string, keys ...string) (Filters, error) {
+	parsed := Filters{}
+	for _, filter := range filters {
+		key, value, ok := strings.Cut(filter, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid filter %q, the format is key=value", filter)
+		}
+		key = strings.ToLower(key)
+		if !slices.Contains(keys, key) {
+			return nil, fmt.Errorf("invalid filter %q, the key must be %s", filter, joinKeys(keys))
+		}
+		parsed[key] = append(parsed[key], value)
+	}
+	return parsed, nil
+}
+
+// MatchAny the key is matched if any value matches, the missing key matches all
+func (f Filters) MatchAny(key string, match func(string) bool) bool {
+	values := f[key]
+	if len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		if match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// e.g. name, driver or label
+func joinKeys(keys []string) string {
+	if len(keys) < 2 {
+		return strings.Join(keys, "")
+	}
+	return strings.Join(keys[:len(keys)-1], ", ") + " or " + keys[len(keys)-1]
+}
@@ -0,0 +1,77 @@
+package label
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Parse parse the labels of --label key=value and the files of --label-file,
+// the labels of the flags override the labels in the files. the value of the
+// label without = is empty
+func Parse(labels []string, files []string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for _, file := range files {
+		lines, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := parseInto(parsed, lines); err != nil {
+			return nil, fmt.Errorf("parse label file %s error %v", file, err)
+		}
+	}
+	if err := parseInto(parsed, labels); err != nil {
+		return nil, err
+	}
+	if len(parsed) == 0 {
+		return nil, nil
+	}
+	return parsed, nil
+}
+
+func parseInto(parsed map[string]string, labels []string) error {
+	for _, label := range labels {
+		key, value, _ := strings.Cut(label, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return fmt.Errorf("invalid label %q, the format is key=value", label)
+		}
+		parsed[key] = value
+	}
+	return nil
+}
+
+// the label file has a label per line, the empty lines and the lines starting
+// with # are ignored
+func readFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open label file error %v", err)
+	}
+	defer f.Close()
+	lines := []string{}
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scan.Err(); err != nil {
+		return nil, fmt.Errorf("read label file %s error %v", path, err)
+	}
+	return lines, nil
+}
+
+// Match check whether the labels match the filter, the filter is the key or
+// key=value
+func Match(labels map[string]string, filter string) bool {
+	key, value, hasValue := strings.Cut(filter, "=")
+	actual, ok := labels[key]
+	if !ok {
+		return false
+	}
+	return !hasValue || actual == value
+}
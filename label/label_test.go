@@ -0,0 +1,55 @@
+package label
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+	file := filepath.Join(t.TempDir(), "labels")
+	os.WriteFile(file, []byte("# owner\nteam=infra\n\napp=web\nurl=http://a?b=c\n"), 0644)
+	labels, err := Parse([]string{"app=api", "canary"}, []string{file})
+	assert.Nil(err)
+	assert.Equal(map[string]string{"team": "infra", "app": "api", "url": "http://a?b=c", "canary": ""}, labels)
+
+	labels, err = Parse(nil, nil)
+	assert.Nil(err)
+	assert.Nil(labels)
+	_, err = Parse([]string{"=web"}, nil)
+	assert.NotNil(err)
+	_, err = Parse(nil, []string{filepath.Join(t.TempDir(), "missing")})
+	assert.NotNil(err)
+}
+
+func TestMatch(t *testing.T) {
+	assert := assert.New(t)
+	labels := map[string]string{"team": "infra", "canary": ""}
+	assert.True(Match(labels, "team"))
+	assert.True(Match(labels, "team=infra"))
+	assert.True(Match(labels, "canary="))
+	assert.False(Match(labels, "team=web"))
+	assert.False(Match(labels, "owner"))
+	assert.False(Match(nil, "team"))
+}
+
+func TestFilters(t *testing.T) {
+	assert := assert.New(t)
+	f, err := ParseFilters([]string{"NAME=web", "name=api", "label=team=infra"}, "name", "label")
+	assert.Nil(err)
+	assert.Equal(Filters{"name": {"web", "api"}, "label": {"team=infra"}}, f)
+	assert.True(f.MatchAny("name", func(name string) bool { return name == "api" }))
+	assert.False(f.MatchAny("label", func(string) bool { return false }))
+	// the missing key matches all
+	assert.True(f.MatchAny("driver", func(string) bool { return false }))
+
+	_, err = ParseFilters([]string{"id=abc"}, "name", "driver", "label")
+	assert.EqualError(err, `invalid filter "id=abc", the key must be name, driver or label`)
+	for _, filter := range []string{"name", "name="} {
+		_, err := ParseFilters([]string{filter}, "name")
+		assert.NotNil(err, filter)
+	}
+}
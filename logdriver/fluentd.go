@@ -0,0 +1,142 @@
+package logdriver
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	optFluentdAddress = "fluentd-address"
+	// the default address of the fluentd forward input
+	defaultFluentdAddress = "localhost:24224"
+	fluentdTimeout        = 5 * time.Second
+)
+
+// Fluentd send the log to fluentd by the forward protocol, every line is an
+// event of the message mode, reference: https://github.com/fluent/fluentd/wiki/Forward-Protocol-Specification-v1
+type Fluentd struct{}
+
+func (f *Fluentd) Name() string {
+	return "fluentd"
+}
+
+func (f *Fluentd) Validate(opts map[string]string) error {
+	if err := validateKeys(f.Name(), opts, optFluentdAddress, optTag); err != nil {
+		return err
+	}
+	_, _, err := parseFluentdAddress(opts[optFluentdAddress])
+	return err
+}
+
+func (f *Fluentd) New(info *Info) (Logger, error) {
+	network, address, err := parseFluentdAddress(info.Opts[optFluentdAddress])
+	if err != nil {
+		return nil, err
+	}
+	logger := &fluentdLogger{
+		network: network,
+		address: address,
+		tag:     tag(info),
+		info:    info,
+	}
+	if err := logger.connect(); err != nil {
+		return nil, err
+	}
+	return logger, nil
+}
+
+type fluentdLogger struct {
+	mu      sync.Mutex
+	network string
+	address string
+	tag     string
+	info    *Info
+	conn    net.Conn
+}
+
+func (l *fluentdLogger) connect() error {
+	conn, err := net.DialTimeout(l.network, l.address, fluentdTimeout)
+	if err != nil {
+		return fmt.Errorf("connect to fluentd %s error %v", l.address, err)
+	}
+	l.conn = conn
+	return nil
+}
+
+func (l *fluentdLogger) Log(msg *Message) error {
+	// [tag, time, record]
+	event := appendArrayHeader(nil, 3)
+	event = appendString(event, l.tag)
+	event = appendEventTime(event, msg.Time)
+	event = appendMapHeader(event, 4)
+	event = appendString(event, "container_id")
+	event = appendString(event, l.info.ContainerID)
+	event = appendString(event, "container_name")
+	event = appendString(event, l.info.ContainerName)
+	event = appendString(event, "source")
+	event = appendString(event, msg.Stream)
+	event = appendString(event, "log")
+	event = appendString(event, string(msg.Line))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// reconnect once if fluentd is restarted
+	for retry := 0; ; retry++ {
+		if l.conn == nil {
+			if err := l.connect(); err != nil {
+				return err
+			}
+		}
+		l.conn.SetWriteDeadline(time.Now().Add(fluentdTimeout))
+		_, err := l.conn.Write(event)
+		if err == nil {
+			return nil
+		}
+		l.conn.Close()
+		l.conn = nil
+		if retry > 0 {
+			return fmt.Errorf("send log to fluentd %s error %v", l.address, err)
+		}
+	}
+}
+
+func (l *fluentdLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil {
+		return nil
+	}
+	return l.conn.Close()
+}
+
+// parse the address, e.g. localhost:24224, tcp://127.0.0.1:24224,
+// unix:///var/run/fluentd.sock
+func parseFluentdAddress(address string) (string, string, error) {
+	if address == "" {
+		return "tcp", defaultFluentdAddress, nil
+	}
+	u, err := url.Parse(address)
+	if err != nil || u.Scheme == "" || u.Opaque != "" {
+		// host:port without the protocol
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return "", "", fmt.Errorf("invalid %s %q", optFluentdAddress, address)
+		}
+		return "tcp", address, nil
+	}
+	switch u.Scheme {
+	case "tcp":
+		if _, _, err := net.SplitHostPort(u.Host); err != nil {
+			return "", "", fmt.Errorf("invalid %s %q", optFluentdAddress, address)
+		}
+		return "tcp", u.Host, nil
+	case "unix":
+		if u.Path == "" {
+			return "", "", fmt.Errorf("invalid %s %q, the path is empty", optFluentdAddress, address)
+		}
+		return "unix", u.Path, nil
+	}
+	return "", "", fmt.Errorf("invalid %s %q, the protocol must be tcp or unix", optFluentdAddress, address)
+}
@@ -0,0 +1,40 @@
+package logdriver
+
+import "mini-docker/container"
+
+// JSONFile write the log as json lines, it's the only driver that the logs
+// command can read
+type JSONFile struct{}
+
+func (j *JSONFile) Name() string {
+	return container.JSONFileLogDriver
+}
+
+func (j *JSONFile) Validate(opts map[string]string) error {
+	_, err := container.NewLogOptions(opts)
+	return err
+}
+
+func (j *JSONFile) New(info *Info) (Logger, error) {
+	opts, err := container.NewLogOptions(info.Opts)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := container.NewJSONLogWriter(info.LogPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonFileLogger{writer: writer}, nil
+}
+
+type jsonFileLogger struct {
+	writer *container.JSONLogWriter
+}
+
+func (l *jsonFileLogger) Log(msg *Message) error {
+	return l.writer.WriteEntry(&container.LogEntry{Log: string(msg.Line), Stream: msg.Stream, Time: msg.Time})
+}
+
+func (l *jsonFileLogger) Close() error {
+	return l.writer.Close()
+}
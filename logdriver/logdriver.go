@@ -0,0 +1,121 @@
+package logdriver
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+func init() {
+	for _, driver := range []LogDriver{&JSONFile{}, &None{}, &Syslog{}, &Fluentd{}} {
+		drivers[driver.Name()] = driver
+	}
+}
+
+// Validate check the log driver and its options
+func Validate(driverName string, opts map[string]string) error {
+	driver, ok := drivers[driverName]
+	if !ok {
+		return fmt.Errorf("unknown log driver %q", driverName)
+	}
+	return driver.Validate(opts)
+}
+
+// Writer split the output of the container into lines for the logger, the
+// partial line is buffered until its newline is written
+type Writer struct {
+	mu      sync.Mutex
+	logger  Logger
+	partial map[string][]byte
+}
+
+// New create the logger of the log driver
+func New(driverName string, info *Info) (*Writer, error) {
+	driver, ok := drivers[driverName]
+	if !ok {
+		return nil, fmt.Errorf("unknown log driver %q", driverName)
+	}
+	logger, err := driver.New(info)
+	if err != nil {
+		return nil, fmt.Errorf("create %s logger error %v", driverName, err)
+	}
+	return &Writer{logger: logger, partial: make(map[string][]byte)}, nil
+}
+
+// WriteLog log the output of the stream, every line is a message
+func (w *Writer) WriteLog(stream string, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now().UTC()
+	buf := append(w.partial[stream], data...)
+	w.partial[stream] = nil
+	for {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.log(stream, buf[:i+1], now); err != nil {
+			return err
+		}
+		buf = buf[i+1:]
+	}
+	if len(buf) >= maxLineSize {
+		return w.log(stream, buf, now)
+	}
+	w.partial[stream] = append([]byte(nil), buf...)
+	return nil
+}
+
+// Flush log the partial lines
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now().UTC()
+	for stream, buf := range w.partial {
+		if len(buf) == 0 {
+			continue
+		}
+		if err := w.log(stream, buf, now); err != nil {
+			return err
+		}
+	}
+	w.partial = make(map[string][]byte)
+	return nil
+}
+
+func (w *Writer) Close() error {
+	err := w.Flush()
+	if cerr := w.logger.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (w *Writer) log(stream string, line []byte, now time.Time) error {
+	// the logger may keep the message, the buffer is reused
+	msg := &Message{Line: append([]byte(nil), line...), Stream: stream, Time: now}
+	return w.logger.Log(msg)
+}
+
+// get the tag option, the default is the short id of the container
+func tag(info *Info) string {
+	if tag, ok := info.Opts[optTag]; ok && tag != "" {
+		return tag
+	}
+	if len(info.ContainerID) > 12 {
+		return info.ContainerID[:12]
+	}
+	return info.ContainerID
+}
+
+// check the options are supported by the driver
+func validateKeys(driverName string, opts map[string]string, keys ...string) error {
+	for key := range opts {
+		if !slices.Contains(keys, key) {
+			return fmt.Errorf("unknown log option %q for log driver %s", key, driverName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package logdriver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) Log(msg *Message) error {
+	l.lines = append(l.lines, msg.Stream+":"+string(msg.Line))
+	return nil
+}
+
+func (l *testLogger) Close() error {
+	return nil
+}
+
+func TestWriter(t *testing.T) {
+	assert := assert.New(t)
+	logger := &testLogger{}
+	w := &Writer{logger: logger, partial: make(map[string][]byte)}
+	assert.Nil(w.WriteLog("stdout", []byte("hello ")))
+	assert.Nil(w.WriteLog("stderr", []byte("error\n")))
+	assert.Nil(w.WriteLog("stdout", []byte("world\nfoo\nbar")))
+	assert.Equal([]string{"stderr:error\n", "stdout:hello world\n", "stdout:foo\n"}, logger.lines)
+	assert.Nil(w.Close())
+	assert.Equal("stdout:bar", logger.lines[3])
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+	assert.Nil(Validate("json-file", map[string]string{"max-size": "10m", "max-file": "3"}))
+	assert.Nil(Validate("none", nil))
+	assert.Nil(Validate("syslog", map[string]string{"syslog-address": "udp://127.0.0.1", "syslog-facility": "local0", "tag": "web"}))
+	assert.Nil(Validate("fluentd", map[string]string{"fluentd-address": "127.0.0.1:24224"}))
+	assert.NotNil(Validate("journald", nil))
+	assert.NotNil(Validate("none", map[string]string{"tag": "web"}))
+	assert.NotNil(Validate("syslog", map[string]string{"syslog-address": "http://127.0.0.1"}))
+	assert.NotNil(Validate("syslog", map[string]string{"syslog-facility": "web"}))
+	assert.NotNil(Validate("fluentd", map[string]string{"fluentd-address": "udp://127.0.0.1:24224"}))
+}
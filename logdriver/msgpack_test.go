@@ -0,0 +1,21 @@
+package logdriver
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMsgpack(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]byte{0x93}, appendArrayHeader(nil, 3))
+	assert.Equal([]byte{0xdc, 0x00, 0x10}, appendArrayHeader(nil, 16))
+	assert.Equal([]byte{0x84}, appendMapHeader(nil, 4))
+	assert.Equal([]byte{0xa3, 'l', 'o', 'g'}, appendString(nil, "log"))
+	assert.Equal([]byte{0xd9, 0x20}, appendString(nil, strings.Repeat("a", 32))[:2])
+	assert.Equal([]byte{0xda, 0x01, 0x00}, appendString(nil, strings.Repeat("a", 256))[:3])
+	eventTime := time.Unix(1, 2)
+	assert.Equal([]byte{0xd7, 0x00, 0, 0, 0, 1, 0, 0, 0, 2}, appendEventTime(nil, eventTime))
+}
@@ -0,0 +1,26 @@
+package logdriver
+
+// None drop the output of the container
+type None struct{}
+
+func (n *None) Name() string {
+	return "none"
+}
+
+func (n *None) Validate(opts map[string]string) error {
+	return validateKeys(n.Name(), opts)
+}
+
+func (n *None) New(info *Info) (Logger, error) {
+	return &noneLogger{}, nil
+}
+
+type noneLogger struct{}
+
+func (l *noneLogger) Log(msg *Message) error {
+	return nil
+}
+
+func (l *noneLogger) Close() error {
+	return nil
+}
@@ -0,0 +1,46 @@
+package logdriver
+
+import "time"
+
+// log driver
+type LogDriver interface {
+	// return the driver name
+	Name() string
+	// check the log options of the driver
+	Validate(opts map[string]string) error
+	// create the logger of the container
+	New(info *Info) (Logger, error)
+}
+
+// the logger sends the output of a container
+type Logger interface {
+	// log a line of the output
+	Log(msg *Message) error
+	Close() error
+}
+
+// a line of the container output
+type Message struct {
+	Line []byte
+	// stdout or stderr
+	Stream string
+	Time   time.Time
+}
+
+// the container information used by the logger
+type Info struct {
+	ContainerID   string
+	ContainerName string
+	// the path of the json-file log
+	LogPath string
+	Opts    map[string]string
+}
+
+var drivers map[string]LogDriver = make(map[string]LogDriver)
+
+const (
+	// the option shared by syslog and fluentd, the default is the short id
+	optTag = "tag"
+	// the partial line is logged as a line if it's too long
+	maxLineSize = 16 * 1024
+)
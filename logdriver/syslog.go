@@ -0,0 +1,128 @@
+package logdriver
+
+import (
+	"bytes"
+	"fmt"
+	"log/syslog"
+	"mini-docker/container"
+	"net"
+	"net/url"
+)
+
+const (
+	optSyslogAddress  = "syslog-address"
+	optSyslogFacility = "syslog-facility"
+)
+
+var syslogFacilities = map[string]syslog.Priority{
+	"kern":     syslog.LOG_KERN,
+	"user":     syslog.LOG_USER,
+	"mail":     syslog.LOG_MAIL,
+	"daemon":   syslog.LOG_DAEMON,
+	"auth":     syslog.LOG_AUTH,
+	"syslog":   syslog.LOG_SYSLOG,
+	"lpr":      syslog.LOG_LPR,
+	"news":     syslog.LOG_NEWS,
+	"uucp":     syslog.LOG_UUCP,
+	"cron":     syslog.LOG_CRON,
+	"authpriv": syslog.LOG_AUTHPRIV,
+	"ftp":      syslog.LOG_FTP,
+	"local0":   syslog.LOG_LOCAL0,
+	"local1":   syslog.LOG_LOCAL1,
+	"local2":   syslog.LOG_LOCAL2,
+	"local3":   syslog.LOG_LOCAL3,
+	"local4":   syslog.LOG_LOCAL4,
+	"local5":   syslog.LOG_LOCAL5,
+	"local6":   syslog.LOG_LOCAL6,
+	"local7":   syslog.LOG_LOCAL7,
+}
+
+// Syslog send the log to the local syslog(/dev/log) or a remote syslog
+// server, the stdout is logged as info and the stderr as error
+type Syslog struct{}
+
+func (s *Syslog) Name() string {
+	return "syslog"
+}
+
+func (s *Syslog) Validate(opts map[string]string) error {
+	if err := validateKeys(s.Name(), opts, optSyslogAddress, optSyslogFacility, optTag); err != nil {
+		return err
+	}
+	if _, _, err := parseSyslogAddress(opts[optSyslogAddress]); err != nil {
+		return err
+	}
+	_, err := parseSyslogFacility(opts[optSyslogFacility])
+	return err
+}
+
+func (s *Syslog) New(info *Info) (Logger, error) {
+	network, address, err := parseSyslogAddress(info.Opts[optSyslogAddress])
+	if err != nil {
+		return nil, err
+	}
+	facility, err := parseSyslogFacility(info.Opts[optSyslogFacility])
+	if err != nil {
+		return nil, err
+	}
+	// the writer reconnects after the write fails
+	writer, err := syslog.Dial(network, address, facility|syslog.LOG_INFO, tag(info))
+	if err != nil {
+		return nil, err
+	}
+	return &syslogLogger{writer: writer}, nil
+}
+
+type syslogLogger struct {
+	writer *syslog.Writer
+}
+
+func (l *syslogLogger) Log(msg *Message) error {
+	line := string(bytes.TrimRight(msg.Line, "\r\n"))
+	if msg.Stream == container.LogStreamStderr {
+		return l.writer.Err(line)
+	}
+	return l.writer.Info(line)
+}
+
+func (l *syslogLogger) Close() error {
+	return l.writer.Close()
+}
+
+// parse the address, e.g. unix:///dev/log, udp://127.0.0.1:514. the empty
+// address is the local syslog
+func parseSyslogAddress(address string) (string, string, error) {
+	if address == "" {
+		return "", "", nil
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid %s %q", optSyslogAddress, address)
+	}
+	switch u.Scheme {
+	case "unix", "unixgram":
+		if u.Path == "" {
+			return "", "", fmt.Errorf("invalid %s %q, the path is empty", optSyslogAddress, address)
+		}
+		return u.Scheme, u.Path, nil
+	case "udp", "tcp":
+		host := u.Host
+		// the default port of syslog
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, "514")
+		}
+		return u.Scheme, host, nil
+	}
+	return "", "", fmt.Errorf("invalid %s %q, the protocol must be unix, unixgram, udp or tcp", optSyslogAddress, address)
+}
+
+func parseSyslogFacility(facility string) (syslog.Priority, error) {
+	if facility == "" {
+		return syslog.LOG_DAEMON, nil
+	}
+	priority, ok := syslogFacilities[facility]
+	if !ok {
+		return 0, fmt.Errorf("invalid %s %q", optSyslogFacility, facility)
+	}
+	return priority, nil
+}
@@ -5,14 +5,18 @@ import (
 	"mini-docker/logger"
 	_ "mini-docker/nsenter"
 	_ "mini-docker/config"
+	"os"
 
 	"go.uber.org/zap"
 )
 
 func main() {
 	logger := logger.CreateLogger()
-	defer logger.Sync()
-
 	zap.ReplaceGlobals(logger)
-	cmd.Execute()
+	err := cmd.Execute()
+	logger.Sync()
+	// the failed command exits with non-zero code
+	if err != nil {
+		os.Exit(1)
+	}
 }
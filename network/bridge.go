@@ -156,6 +156,11 @@ func setInterfaceUP(bridgeName string) error {
 
 func setIPTables(bridgeName string, subnet *net.IPNet) error {
 	iptablesArgs := fmt.Sprintf("-t nat -A POSTROUTING -s %s ! -o %s -j MASQUERADE", subnet.String(), bridgeName)
+	// the rule may outlive the bridge, e.g. the restored rules after reboot
+	check := strings.Replace(iptablesArgs, " -A ", " -C ", 1)
+	if exec.Command("iptables", strings.Split(check, " ")...).Run() == nil {
+		return nil
+	}
 	cmd := exec.Command("iptables", strings.Split(iptablesArgs, " ")...)
 	output, err := cmd.Output()
 	if err != nil {
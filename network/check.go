@@ -0,0 +1,196 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+)
+
+// NATRule the iptables rule added by mini-docker, which is the masquerade
+// rule of the network or the port mapping of the container
+type NATRule struct {
+	// the masquerade rule, -s {subnet} ! -o {bridge} -j MASQUERADE
+	Subnet *net.IPNet
+	Bridge string
+	// the port mapping rule, --dport {host port} -j DNAT --to-destination {ip}:{port}
+	HostPort string
+	IP       net.IP
+	Port     string
+	// the rule without the action, e.g. POSTROUTING -s ...
+	rule []string
+}
+
+func (r *NATRule) String() string {
+	return strings.Join(r.rule, " ")
+}
+
+// Networks list the networks loaded by Init
+func Networks() []*NetWork {
+	list := []*NetWork{}
+	for _, nw := range networks {
+		list = append(list, nw)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// DeviceExists check whether the device of the network exists, the bridge is
+// lost after the host reboots
+func DeviceExists(nw *NetWork) bool {
+	_, err := netlink.LinkByName(nw.Name)
+	return err == nil
+}
+
+// RestoreNetwork create the device of the network again
+func RestoreNetwork(nw *NetWork) error {
+	bridge, ok := devices[nw.Driver].(*Bridge)
+	if !ok {
+		return fmt.Errorf("the network driver %s can't be restored", nw.Driver)
+	}
+	return bridge.initBridge(nw)
+}
+
+// EndPointDevices list the veths attached to the bridge of the network
+func EndPointDevices(nw *NetWork) ([]string, error) {
+	bridge, err := netlink.LinkByName(nw.Name)
+	if err != nil {
+		return nil, err
+	}
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("list links error %v", err)
+	}
+	names := []string{}
+	for _, link := range links {
+		if link.Type() == "veth" && link.Attrs().MasterIndex == bridge.Attrs().Index {
+			names = append(names, link.Attrs().Name)
+		}
+	}
+	return names, nil
+}
+
+// DeleteDevice delete the device, the peer of the veth is deleted together
+func DeleteDevice(name string) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return err
+	}
+	return netlink.LinkDel(link)
+}
+
+// AllocatedIPs list the allocated ips of every subnet in the ipam config
+func AllocatedIPs() (map[string][]net.IP, error) {
+	unlock, err := ipamAllocator.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	ipamAllocator.Subnets = &map[string][]int64{}
+	if err := ipamAllocator.load(); err != nil {
+		return nil, err
+	}
+	allocated := map[string][]net.IP{}
+	for subnet, bitmap := range *ipamAllocator.Subnets {
+		_, ipnet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return nil, fmt.Errorf("parse subnet %s error %v", subnet, err)
+		}
+		allocated[subnet] = bitmapIPs(ipnet, bitmap)
+	}
+	return allocated, nil
+}
+
+// the ips of the bitmap, the bit 63-k of the idx-th word is the ip with the
+// offset 64*idx+k+1 in the subnet
+func bitmapIPs(subnet *net.IPNet, bitmap []int64) []net.IP {
+	base := subnet.IP.To4()
+	ips := []net.IP{}
+	for idx, v := range bitmap {
+		for k := 0; k < 64; k++ {
+			if v>>(63-k)&1 == 0 {
+				continue
+			}
+			n := uint32(64*idx + k + 1)
+			ip := make(net.IP, 4)
+			for j := 0; j < 4; j++ {
+				ip[j] = base[j] + uint8(n>>(8*(3-j)))
+			}
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// ReleaseIP release the ip of the subnet
+func ReleaseIP(subnet *net.IPNet, ip net.IP) error {
+	return ipamAllocator.Release(subnet, &ip)
+}
+
+// RemoveSubnet remove the subnet and all its ips from the ipam config
+func RemoveSubnet(subnet *net.IPNet) error {
+	return ipamAllocator.RemoveSubNet(subnet)
+}
+
+// NATRules list the masquerade and the port mapping rules in the nat table
+func NATRules() ([]*NATRule, error) {
+	output, err := exec.Command("iptables", "-t", "nat", "-S").Output()
+	if err != nil {
+		return nil, fmt.Errorf("list iptables rules error %v", err)
+	}
+	rules := []*NATRule{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if rule := parseNATRule(line); rule != nil {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// parse the rule printed by iptables -S, only the rules in the format of
+// setIPTables and configPortMap are returned
+func parseNATRule(line string) *NATRule {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "-A" {
+		return nil
+	}
+	rule := &NATRule{rule: fields[1:]}
+	args := strings.Join(fields[2:], " ")
+	switch fields[1] {
+	case "POSTROUTING":
+		var subnet, bridge string
+		if n, _ := fmt.Sscanf(args, "-s %s ! -o %s -j MASQUERADE", &subnet, &bridge); n != 2 || !strings.HasSuffix(args, "-j MASQUERADE") {
+			return nil
+		}
+		_, ipnet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return nil
+		}
+		rule.Subnet, rule.Bridge = ipnet, bridge
+	case "PREROUTING":
+		var hostPort, dest string
+		if n, _ := fmt.Sscanf(args, "-p tcp -m tcp --dport %s -j DNAT --to-destination %s", &hostPort, &dest); n != 2 {
+			return nil
+		}
+		host, port, err := net.SplitHostPort(dest)
+		if err != nil || net.ParseIP(host) == nil {
+			return nil
+		}
+		rule.HostPort, rule.IP, rule.Port = hostPort, net.ParseIP(host), port
+	default:
+		return nil
+	}
+	return rule
+}
+
+// Delete remove the rule from the nat table
+func (r *NATRule) Delete() error {
+	args := append([]string{"-t", "nat", "-D"}, r.rule...)
+	if output, err := exec.Command("iptables", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("delete iptables rule error %v, output %s", err, output)
+	}
+	return nil
+}
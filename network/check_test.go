@@ -0,0 +1,44 @@
+package network
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNATRule(t *testing.T) {
+	assert := assert.New(t)
+	rule := parseNATRule("-A POSTROUTING -s 10.0.0.0/24 ! -o br0 -j MASQUERADE")
+	assert.NotNil(rule)
+	assert.Equal("10.0.0.0/24", rule.Subnet.String())
+	assert.Equal("br0", rule.Bridge)
+	assert.Equal("POSTROUTING -s 10.0.0.0/24 ! -o br0 -j MASQUERADE", rule.String())
+
+	rule = parseNATRule("-A PREROUTING -p tcp -m tcp --dport 8080 -j DNAT --to-destination 10.0.0.2:80")
+	assert.NotNil(rule)
+	assert.Equal("8080", rule.HostPort)
+	assert.Equal("10.0.0.2", rule.IP.String())
+	assert.Equal("80", rule.Port)
+
+	for _, line := range []string{
+		"-P PREROUTING ACCEPT",
+		"-N DOCKER",
+		"-A POSTROUTING -s 172.17.0.0/16 ! -o docker0 -j RETURN",
+		"-A PREROUTING -m addrtype --dst-type LOCAL -j DOCKER",
+	} {
+		assert.Nil(parseNATRule(line), line)
+	}
+}
+
+func TestBitmapIPs(t *testing.T) {
+	assert := assert.New(t)
+	_, subnet, _ := net.ParseCIDR("10.0.0.0/16")
+	// the offsets 1, 2 and 65
+	bitmap := []int64{-1 << 62, -1 << 63}
+	ips := []string{}
+	for _, ip := range bitmapIPs(subnet, bitmap) {
+		ips = append(ips, ip.String())
+	}
+	assert.Equal([]string{"10.0.0.1", "10.0.0.2", "10.0.0.65"}, ips)
+}
@@ -0,0 +1,67 @@
+package network
+
+import (
+	"fmt"
+	"mini-docker/container"
+	"mini-docker/label"
+	"os"
+	"strings"
+)
+
+// the keys of the network filter
+const (
+	filterName   = "name"
+	filterDriver = "driver"
+	filterLabel  = "label"
+)
+
+// Filters the filters of the network list and prune, e.g. label=team=infra
+type Filters struct {
+	label.Filters
+}
+
+// ParseFilters parse the filters in the format of key=value
+func ParseFilters(filters []string) (*Filters, error) {
+	parsed, err := label.ParseFilters(filters, filterName, filterDriver, filterLabel)
+	if err != nil {
+		return nil, err
+	}
+	return &Filters{parsed}, nil
+}
+
+// Match check whether the network matches all the filters
+func (f *Filters) Match(nw *NetWork) bool {
+	return f.MatchAny(filterName, func(name string) bool { return strings.Contains(nw.Name, name) }) &&
+		f.MatchAny(filterDriver, func(driver string) bool { return nw.Driver == driver }) &&
+		f.MatchAny(filterLabel, func(filter string) bool { return label.Match(nw.Labels, filter) })
+}
+
+// PruneNetworks remove the networks which match the filters and aren't used
+// by any container, the networks are loaded by Init. the removed networks are
+// returned even if some of the networks fail to be removed
+func PruneNetworks(filters []string) ([]string, error) {
+	f, err := ParseFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := container.GetAllContainers()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("get all containers error %v", err)
+	}
+	// the stopped container keeps its ip until it's removed
+	used := map[string]bool{}
+	for _, meta := range containers {
+		used[meta.Network] = true
+	}
+	removed := []string{}
+	for _, nw := range Networks() {
+		if used[nw.Name] || !f.Match(nw) {
+			continue
+		}
+		if err := RemoveNetwork(nw.Name); err != nil {
+			return removed, fmt.Errorf("remove network %s error %v", nw.Name, err)
+		}
+		removed = append(removed, nw.Name)
+	}
+	return removed, nil
+}
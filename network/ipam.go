@@ -3,6 +3,7 @@ package network
 import (
 	"encoding/json"
 	"fmt"
+	"mini-docker/store"
 	"net"
 	"os"
 	"path/filepath"
@@ -25,67 +26,55 @@ func NewIPAM(ipamAllocatorPath string) *IPAM {
 	}
 }
 
-// load the ipnet ip allocation
-func (m *IPAM) load() error {
-	if _, err := os.Stat(m.SubnetAllocatorPath); err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
-	}
+// the ipam config, the bitmap of the allocated ips of every subnet
+type ipamConfig struct {
+	store.Versioned
+	Subnets map[string][]int64 `json:"subnets"`
+}
 
-	f, err := os.Open(m.SubnetAllocatorPath)
-	if err != nil {
-		return err
+// the config of version 0 is the map of the subnets
+func migrateIPAM(version int, data []byte) ([]byte, error) {
+	if version == 0 {
+		return json.Marshal(map[string]json.RawMessage{"subnets": data})
 	}
-	defer f.Close()
+	return data, nil
+}
 
-	buffer := make([]byte, 2000)
-	n, err := f.Read(buffer)
-	if err != nil {
-		return err
-	}
+// the store of the ipam config and the key of the config
+func (m *IPAM) configStore() (*store.Store, string) {
+	dir, file := filepath.Split(m.SubnetAllocatorPath)
+	return store.New(dir, "%s", ipamSchemaVersion, migrateIPAM), file
+}
 
-	err = json.Unmarshal(buffer[:n], m.Subnets)
-	if err != nil {
-		zap.L().Sugar().Errorf("unmarshal ipam config file error %v", err)
-		return err
-	}
-	return nil
+// lock the ipam config, the allocation is serialized between the processes
+func (m *IPAM) lock() (func(), error) {
+	s, key := m.configStore()
+	return s.Lock(key)
 }
 
-// storage ip allocate
-func (m *IPAM) storage() error {
-	ipamConfigPath, _ := filepath.Split(m.SubnetAllocatorPath)
-	if _, err := os.Stat(ipamConfigPath); err != nil {
+// load the ipnet ip allocation
+func (m *IPAM) load() error {
+	s, key := m.configStore()
+	cfg := &ipamConfig{}
+	if err := s.Get(key, cfg); err != nil {
 		if os.IsNotExist(err) {
-			if err := os.MkdirAll(ipamConfigPath, 0644); err != nil {
-				zap.L().Sugar().Errorf("mkdir dir %s error %v", ipamConfigPath, err)
-				return err
-			}
-		} else {
-			return err
+			return nil
 		}
-	}
-
-	f, err := os.OpenFile(m.SubnetAllocatorPath, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	ipamJSON, err := json.Marshal(m.Subnets)
-	if err != nil {
+		zap.L().Sugar().Errorf("load ipam config file error %v", err)
 		return err
 	}
-
-	_, err = f.Write(ipamJSON)
-	if err != nil {
-		return err
+	if cfg.Subnets != nil {
+		m.Subnets = &cfg.Subnets
 	}
 	return nil
 }
 
+// storage ip allocate, the lock is held
+func (m *IPAM) storage() error {
+	s, key := m.configStore()
+	return s.Write(key, &ipamConfig{Subnets: *m.Subnets})
+}
+
 // allocate ip address
 func (m *IPAM) Allocate(subnet *net.IPNet) (*net.IP, error) {
 	_, subnet, _ = net.ParseCIDR(subnet.String())
@@ -95,9 +84,14 @@ func (m *IPAM) Allocate(subnet *net.IPNet) (*net.IP, error) {
 	var cnt, total int64 
 	cnt = 1 << max(delta - 6, 0)
 	total = 1 << delta - 2
-	
+
+	unlock, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 	m.Subnets = &map[string][]int64{}
-	err := m.load()
+	err = m.load()
 	if err != nil {
 		zap.L().Sugar().Warnf("load the config file error %v", err)
 	}
@@ -137,8 +131,13 @@ func (m *IPAM) Allocate(subnet *net.IPNet) (*net.IP, error) {
 
 // release ip address
 func (m *IPAM) Release(subnet *net.IPNet, oldIP *net.IP) error {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	m.Subnets = &map[string][]int64{}
-	err := m.load()
+	err = m.load()
 	if err != nil {
 		zap.L().Sugar().Warnf("load the ipam config error %v", err)
 		return err
@@ -171,8 +170,13 @@ func (m *IPAM) Release(subnet *net.IPNet, oldIP *net.IP) error {
 
 // remove subnet
 func (m *IPAM) RemoveSubNet(subnet *net.IPNet) error {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	m.Subnets = &map[string][]int64{}
-	err := m.load()
+	err = m.load()
 	if err != nil {
 		zap.L().Sugar().Warnf("load the ipam config error %v", err)
 		return err
@@ -1,13 +1,11 @@
 package network
 
 import (
-	"encoding/json"
 	"fmt"
 	"mini-docker/container"
 	"net"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
 	"strings"
 	"text/tabwriter"
@@ -20,7 +18,7 @@ import (
 // callback mean return origin cyberspace
 type callback func()
 
-func CreateNetwork(name, subnet, device string) error {
+func CreateNetwork(name, subnet, device string, labels map[string]string) error {
 	_, cidr, _ := net.ParseCIDR(subnet)
 	// the first ip
 	gatewayIP, err := ipamAllocator.Allocate(cidr)
@@ -33,38 +31,25 @@ func CreateNetwork(name, subnet, device string) error {
 	if err != nil {
 		return err
 	}
-	return nw.storage(defaultNetworkPath)
+	nw.Labels = labels
+	return nw.storage()
 }
 
 // init
 func Init() error {
-	// check defaultNetworkPath
-	if _, err := os.Stat(defaultNetworkPath); err != nil {
-		if os.IsNotExist(err) {
-			if err := os.MkdirAll(defaultNetworkPath, 0644); err != nil {
-				return fmt.Errorf("mkdir dir %s error %v", defaultNetworkPath, err)
-			}
-		} else {
-			return err
-		}
-	}
 	// init networks
-	files, err := os.ReadDir(defaultNetworkPath)
+	names, err := networkStore.Keys()
 	if err != nil {
 		return fmt.Errorf("read dir %s error %v", defaultNetworkPath, err)
 	}
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-		filename := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+	for _, name := range names {
 		nw := &NetWork{
-			Name: filename,
+			Name: name,
 		}
-		if err := nw.load(filepath.Join(defaultNetworkPath, file.Name())); err != nil {
+		if err := nw.load(); err != nil {
 			return err
 		}
-		networks[filename] = nw
+		networks[name] = nw
 	}
 	// init devices
 	bridge := &Bridge{}
@@ -72,15 +57,41 @@ func Init() error {
 	return nil
 }
 
-func ListNetwork() {
+// ListNetwork print the networks which match the filters
+func ListNetwork(filters []string) error {
+	f, err := ParseFilters(filters)
+	if err != nil {
+		return err
+	}
 	w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
 	fmt.Fprint(w, "NAME\tIPRange\tDriver\n")
-	for _, network := range networks {
+	for _, network := range Networks() {
+		if !f.Match(network) {
+			continue
+		}
 		fmt.Fprintf(w, "%s\t%s\t%s\n", network.Name, network.IPRange, network.Driver)
 	}
 	if err := w.Flush(); err != nil {
 		zap.L().Sugar().Errorf("flush error %v", err)
 	}
+	return nil
+}
+
+// GetNetwork get the network by the name, the networks are loaded by Init
+func GetNetwork(networkName string) (*NetWork, error) {
+	nw, ok := networks[networkName]
+	if !ok {
+		return nil, fmt.Errorf("the network %s don't exist", networkName)
+	}
+	return nw, nil
+}
+
+// the host end of the veth pair of the container, which is created by Connect
+func EndPointDevice(containerID string) string {
+	if len(containerID) < 5 {
+		return containerID
+	}
+	return containerID[:5]
 }
 
 func RemoveNetwork(networkName string) error {
@@ -98,7 +109,8 @@ func RemoveNetwork(networkName string) error {
 	if err != nil {
 		return fmt.Errorf("delete driver %s error %v", devices[nw.Driver].Name(), err)
 	}
-	return nw.remove(networkName)
+	delete(networks, networkName)
+	return nw.remove()
 }
 
 func Connect(networkName string, containerMeta *container.ContainerMeta) error {
@@ -107,10 +119,21 @@ func Connect(networkName string, containerMeta *container.ContainerMeta) error {
 	if !ok {
 		return fmt.Errorf("don't have this network")
 	}
-	// allocate ip
-	ip, err := ipamAllocator.Allocate(nw.IPRange)
-	if err != nil {
-		return fmt.Errorf("allocate ip error %v", err)
+	// allocate ip, the restarted container reuses its ip which is kept until
+	// the container is removed
+	var ip *net.IP
+	if containerMeta.IP != "" {
+		containerIP, _, err := net.ParseCIDR(containerMeta.IP)
+		if err != nil {
+			return fmt.Errorf("parse container ip error %v", err)
+		}
+		ip = &containerIP
+	} else {
+		allocated, err := ipamAllocator.Allocate(nw.IPRange)
+		if err != nil {
+			return fmt.Errorf("allocate ip error %v", err)
+		}
+		ip = allocated
 	}
 	// endpoint
 	ep := &EndPoint{
@@ -119,7 +142,7 @@ func Connect(networkName string, containerMeta *container.ContainerMeta) error {
 		PortMapping: strings.Split(containerMeta.Port, " "),
 		NetWork:     nw,
 	}
-	err = devices[nw.Driver].Connect(nw, ep)
+	err := devices[nw.Driver].Connect(nw, ep)
 	if err != nil {
 		return err
 	}
@@ -154,6 +177,35 @@ func DisConnect(containerName string) error {
 	return ipamAllocator.Release(subnet, &containerIP)
 }
 
+// RemovePortMapping remove the port mapping rules of the container, which is
+// called when the container exits. the ip is released by DisConnect
+func RemovePortMapping(containerName string) error {
+	containerMeta, err := container.GetContainerByName(containerName)
+	if err != nil {
+		return err
+	}
+	if containerMeta.IP == "" || containerMeta.Port == "" {
+		return nil
+	}
+	containerIP, _, err := net.ParseCIDR(containerMeta.IP)
+	if err != nil {
+		return err
+	}
+	for _, pm := range strings.Split(containerMeta.Port, " ") {
+		portMap := strings.Split(pm, ":")
+		if len(portMap) != 2 || portMap[0] == "" || portMap[1] == "" {
+			continue
+		}
+		host, container := portMap[0], portMap[1]
+		iptablesCmd := fmt.Sprintf("-t nat -D PREROUTING -p tcp -m tcp --dport %s -j DNAT --to-destination %s:%s", host, containerIP.String(), container)
+		cmd := exec.Command("iptables", strings.Split(iptablesCmd, " ")...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			zap.L().Sugar().Warnf("remove port mapping %s error %v, iptables output %s", pm, err, output)
+		}
+	}
+	return nil
+}
+
 // config port map
 func configPortMap(ep *EndPoint) error {
 	for _, pm := range ep.PortMapping {
@@ -245,70 +297,18 @@ func enterNetNamespace(eplink *netlink.Link, containerMeta *container.ContainerM
 }
 
 // remove method is mean to remove the configuration of network
-func (nw *NetWork) remove(networkPath string) error {
-	if _, err := os.Stat(networkPath); err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
-	}
-	return os.Remove(networkPath)
+func (nw *NetWork) remove() error {
+	return networkStore.Delete(nw.Name)
 }
 
-func (nw *NetWork) load(networkPath string) error {
-	if _, err := os.Stat(networkPath); err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
-	}
-
-	f, err := os.Open(networkPath)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	buffer := make([]byte, 2000)
-	n, err := f.Read(buffer)
-	if err != nil {
-		return err
-	}
-	err = json.Unmarshal(buffer[:n], nw)
-	if err != nil {
-		zap.L().Sugar().Errorf("unmarshal network config file error %v", err)
+func (nw *NetWork) load() error {
+	if err := networkStore.Get(nw.Name, nw); err != nil {
+		zap.L().Sugar().Errorf("load network config file error %v", err)
 		return err
 	}
 	return nil
 }
 
-func (nw *NetWork) storage(networkPath string) error {
-	if _, err := os.Stat(networkPath); err != nil {
-		if os.IsNotExist(err) {
-			if err := os.MkdirAll(networkPath, 0644); err != nil {
-				zap.L().Sugar().Errorf("mkdir dir %s error %v", networkPath, err)
-				return err
-			}
-		} else {
-			return err
-		}
-	}
-
-	fileName := filepath.Join(networkPath, nw.Name+".json")
-	f, err := os.OpenFile(fileName, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	networkJSON, err := json.Marshal(nw)
-	if err != nil {
-		return err
-	}
-
-	_, err = f.Write(networkJSON)
-	if err != nil {
-		return err
-	}
-	return nil
+func (nw *NetWork) storage() error {
+	return networkStore.Put(nw.Name, nw)
 }
@@ -1,6 +1,7 @@
 package network
 
 import (
+	"mini-docker/store"
 	"net"
 
 	"github.com/vishvananda/netlink"
@@ -22,12 +23,15 @@ type NetWorkDriver interface {
 
 // net
 type NetWork struct {
+	store.Versioned
 	// network name
 	Name string
 	// ipnet
 	IPRange *net.IPNet
 	// network driver
 	Driver string
+	// the user defined metadata, e.g. team=infra
+	Labels map[string]string `json:",omitempty"`
 }
 
 // net point(entry)
@@ -48,4 +52,10 @@ var (
 const (
 	ipamDefaultAllocatorPath = "/var/run/mini-docker/network/ipam/subnet.json"
 	defaultNetworkPath       = "/var/run/mini-docker/network"
+	// the schema versions of the network config and the ipam config
+	networkSchemaVersion = 1
+	ipamSchemaVersion    = 1
 )
+
+// the config of the networks, <name>.json
+var networkStore = store.New(defaultNetworkPath, "%s.json", networkSchemaVersion, nil)
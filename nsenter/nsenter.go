@@ -9,8 +9,18 @@ package nsenter
 #include <errno.h>
 #include <string.h>
 #include <unistd.h>
+#include <poll.h>
+#include <sys/wait.h>
 #define N 1024
+// the pipe of the exec config and the pipe of reporting the error to the
+// parent, 0-2 are stdio
+#define CONFIG_FD 3
+#define READY_FD 4
 
+// enter the namespaces of the container before the go runtime starts, the
+// mnt namespace can't be entered by a multithreaded process. the go runtime
+// runs in the forked child, the threads can't be created by the process that
+// entered another pid namespace
 __attribute__((constructor)) void enter_namespace(void) {
 	char* mini_docker_pid;
 	// get value from environment
@@ -18,23 +28,47 @@ __attribute__((constructor)) void enter_namespace(void) {
 	if(!mini_docker_pid) {
 		return;
 	}
-	char* mini_docker_cmd;
-	mini_docker_cmd = getenv("mini_docker_cmd");
-	if(!mini_docker_cmd) {
-		return;
-	}
 	char *namespace[] = {"ipc", "uts", "net", "pid", "mnt"};
+	int fds[5];
 	char nspath[N];
+	// open all the namespaces first, /proc is changed after entering the mnt namespace
+	for(int i = 0; i < 5; i++) {
+		snprintf(nspath, N, "/proc/%s/ns/%s", mini_docker_pid, namespace[i]);
+		fds[i] = open(nspath, O_RDONLY | O_CLOEXEC);
+		if(fds[i] < 0) {
+			dprintf(READY_FD, "open %s error: %s", nspath, strerror(errno));
+			exit(1);
+		}
+	}
 	for(int i = 0; i < 5; i++) {
-		sprintf(nspath, "/proc/%s/ns/%s", mini_docker_pid, namespace[i]);
-		int fd = open(nspath, O_RDONLY);
 		// entry namespace
-		int t = setns(fd, 0);
-		close(fd);
+		if(setns(fds[i], 0) < 0) {
+			dprintf(READY_FD, "enter %s namespace error: %s", namespace[i], strerror(errno));
+			exit(1);
+		}
+		close(fds[i]);
+	}
+	// the parent writes the config after adding this process to the cgroup
+	// of the container, so the child is in the cgroup
+	struct pollfd pfd = {.fd = CONFIG_FD, .events = POLLIN};
+	while(poll(&pfd, 1, -1) < 0 && errno == EINTR);
+	pid_t pid = fork();
+	if(pid < 0) {
+		dprintf(READY_FD, "fork error: %s", strerror(errno));
+		exit(1);
+	}
+	if(pid == 0) {
+		return;
+	}
+	// the child reports the start of the command
+	close(CONFIG_FD);
+	close(READY_FD);
+	int status;
+	while(waitpid(pid, &status, 0) < 0 && errno == EINTR);
+	if(WIFSIGNALED(status)) {
+		exit(128 + WTERMSIG(status));
 	}
-	int status = system(mini_docker_cmd);
-	exit(0);
-	return;
+	exit(WEXITSTATUS(status));
 }
 
 */
@@ -0,0 +1,156 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mini-docker/container"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// AttachContainer connect the terminal to the stdio of the running container
+// through the shim, it returns after the container exits or the detach keys
+// are typed. the exit code of the container is returned, it's 0 if detached
+func AttachContainer(containerName, detachKeys string) (int, error) {
+	meta, err := container.GetContainerByName(containerName)
+	if err != nil {
+		return -1, fmt.Errorf("get container meta by container name error %v", err)
+	}
+	container.RefreshContainerStatus(meta)
+	if meta.Status != container.RUNING && meta.Status != container.PAUSED {
+		return -1, fmt.Errorf("container %s is %s, only running container can be attached", containerName, meta.Status)
+	}
+	return attachContainer(containerName, detachKeys)
+}
+
+// attach to the container started by run or start without checking the status.
+// the short-lived container may exit before the client attaches, the shim
+// keeps its output in the replay buffer and waits for the client
+func attachContainer(containerName, detachKeys string) (int, error) {
+	keys, err := container.ParseDetachKeys(detachKeys)
+	if err != nil {
+		return -1, err
+	}
+	meta, err := container.GetContainerByName(containerName)
+	if err != nil {
+		return -1, fmt.Errorf("get container meta by container name error %v", err)
+	}
+	sockPath := filepath.Join(fmt.Sprintf(container.DefaultInfoPath, containerName), container.AttachSocket)
+	conn, err := net.Dial("unix", sockPath)
+	if err != nil {
+		return -1, fmt.Errorf("connect to container %s error %v", containerName, err)
+	}
+	defer conn.Close()
+
+	// the frames of stdin and resize are written by different goroutines
+	var mu sync.Mutex
+	send := func(stream byte, payload []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return container.WriteFrame(conn, stream, payload)
+	}
+	isTerminal := container.IsTerminal(os.Stdin.Fd())
+	if meta.TTY && isTerminal {
+		if meta.Interactive {
+			// the control characters are handled by the pty of the container
+			state, err := container.MakeRaw(os.Stdin.Fd())
+			if err != nil {
+				return -1, fmt.Errorf("set the terminal to raw mode error %v", err)
+			}
+			defer container.RestoreTerminal(os.Stdin.Fd(), state)
+		}
+		resize := func() {
+			ws, err := container.GetWinsize(os.Stdin.Fd())
+			if err != nil {
+				zap.L().Sugar().Warnf("get window size error %v", err)
+				return
+			}
+			send(container.StreamResize, container.EncodeWinsize(ws))
+		}
+		resize()
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGWINCH)
+		defer signal.Stop(sigs)
+		go func() {
+			for range sigs {
+				resize()
+			}
+		}()
+	}
+	if !meta.TTY {
+		// the signals of the terminal are sent to the container
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(sigs)
+		go func() {
+			for sig := range sigs {
+				if err := container.KillContainer(containerName, sig.(syscall.Signal)); err != nil {
+					zap.L().Sugar().Warnf("forward signal %v error %v", sig, err)
+				}
+			}
+		}()
+	}
+
+	detached := make(chan struct{})
+	if meta.Interactive {
+		go func() {
+			stdin := container.NewDetachReader(os.Stdin, keys)
+			buf := make([]byte, 32*1024)
+			for {
+				n, err := stdin.Read(buf)
+				if n > 0 {
+					if err := send(container.StreamStdin, buf[:n]); err != nil {
+						return
+					}
+				}
+				if errors.Is(err, container.ErrDetached) {
+					close(detached)
+					// stop reading the output
+					conn.Close()
+					return
+				}
+				if err != nil {
+					// the empty frame closes the stdin of the container
+					send(container.StreamStdin, nil)
+					return
+				}
+			}
+		}()
+	}
+
+	for {
+		stream, payload, err := container.ReadFrame(conn)
+		if err != nil {
+			select {
+			case <-detached:
+				fmt.Fprintf(os.Stderr, "\r\ndetached from container %s\r\n", containerName)
+				return 0, nil
+			default:
+			}
+			if err == io.EOF {
+				return -1, fmt.Errorf("the connection to container %s is closed", containerName)
+			}
+			return -1, fmt.Errorf("read from container %s error %v", containerName, err)
+		}
+		switch stream {
+		case container.StreamStdout:
+			os.Stdout.Write(payload)
+		case container.StreamStderr:
+			os.Stderr.Write(payload)
+		case container.StreamExit:
+			exitCode, err := container.DecodeExitCode(payload)
+			if err != nil {
+				return -1, err
+			}
+			zap.L().Sugar().Infof("container %s exited with code %d", containerName, exitCode)
+			return exitCode, nil
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"mini-docker/config"
+	"mini-docker/container"
+	"mini-docker/network"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// the object types of inspect
+const (
+	InspectContainer = "container"
+	InspectNetwork   = "network"
+	InspectImage     = "image"
+)
+
+// the container information of inspect, the fields of the meta are promoted,
+// e.g. {{.IP}}
+type ContainerInspect struct {
+	*container.ContainerMeta
+	RootFS   string           `json:"rootfs"`
+	LogPath  string           `json:"log_path,omitempty"`
+	Mounts   []InspectMount   `json:"mounts"`
+	Endpoint *EndpointInspect `json:"endpoint,omitempty"`
+}
+
+// the volume of the container
+type InspectMount struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// the endpoint of the container in the network
+type EndpointInspect struct {
+	Network string   `json:"network"`
+	Driver  string   `json:"driver"`
+	Subnet  string   `json:"subnet"`
+	Gateway string   `json:"gateway"`
+	IP      string   `json:"ip"`
+	Device  string   `json:"device"`
+	Ports   []string `json:"ports,omitempty"`
+}
+
+type NetworkInspect struct {
+	Name       string             `json:"name"`
+	Driver     string             `json:"driver"`
+	Subnet     string             `json:"subnet"`
+	Gateway    string             `json:"gateway"`
+	Labels     map[string]string  `json:"labels,omitempty"`
+	Containers []NetworkContainer `json:"containers"`
+}
+
+// the container connected to the network
+type NetworkContainer struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+}
+
+type ImageInspect struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	CreateAt time.Time `json:"create_at"`
+	// the containers created from the image
+	Containers []string `json:"containers"`
+}
+
+// Inspect print the containers, networks or images as a json array, or by the
+// go template of format. the object type is found in the order of container,
+// network and image if objectType is empty
+func Inspect(names []string, objectType, format string) error {
+	var tmpl *template.Template
+	if format != "" {
+		var err error
+		if tmpl, err = container.ParseFormat(format); err != nil {
+			return err
+		}
+	}
+	if err := network.Init(); err != nil {
+		return fmt.Errorf("network init error %v", err)
+	}
+	objects := []interface{}{}
+	for _, name := range names {
+		object, err := inspectObject(name, objectType)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, object)
+	}
+	if tmpl == nil {
+		data, err := json.MarshalIndent(objects, "", "    ")
+		if err != nil {
+			return fmt.Errorf("marshal inspect error %v", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	for _, object := range objects {
+		if err := container.ExecuteFormat(os.Stdout, tmpl, object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func inspectObject(name, objectType string) (interface{}, error) {
+	switch objectType {
+	case InspectContainer:
+		return inspectContainer(name)
+	case InspectNetwork:
+		return inspectNetwork(name)
+	case InspectImage:
+		return inspectImage(name)
+	case "":
+	default:
+		return nil, fmt.Errorf("unknown type %q, the type must be container, network or image", objectType)
+	}
+	if object, err := inspectContainer(name); err == nil {
+		return object, nil
+	}
+	if object, err := inspectNetwork(name); err == nil {
+		return object, nil
+	}
+	if object, err := inspectImage(name); err == nil {
+		return object, nil
+	}
+	return nil, fmt.Errorf("no such object: %s", name)
+}
+
+func inspectContainer(name string) (*ContainerInspect, error) {
+	meta, err := container.GetContainer(name)
+	if err != nil {
+		return nil, err
+	}
+	container.RefreshContainerStatus(meta)
+	info := &ContainerInspect{
+		ContainerMeta: meta,
+		RootFS:        filepath.Join(config.ContainerPath, meta.Name, "merged"),
+		Mounts:        []InspectMount{},
+	}
+	if meta.LogDriver == "" || meta.LogDriver == container.JSONFileLogDriver {
+		info.LogPath = filepath.Join(fmt.Sprintf(container.DefaultInfoPath, meta.Name), container.ContainerLog)
+	}
+	for _, volume := range strings.Fields(meta.Volume) {
+		paths := strings.Split(volume, ":")
+		if len(paths) != 2 || paths[0] == "" || paths[1] == "" {
+			continue
+		}
+		info.Mounts = append(info.Mounts, InspectMount{Source: paths[0], Destination: paths[1]})
+	}
+	if meta.Network != "" {
+		info.Endpoint = &EndpointInspect{
+			Network: meta.Network,
+			IP:      meta.IP,
+			Device:  network.EndPointDevice(meta.ID),
+			Ports:   strings.Fields(meta.Port),
+		}
+		// the network may be removed after the container is created
+		if nw, err := network.GetNetwork(meta.Network); err == nil {
+			info.Endpoint.Driver = nw.Driver
+			info.Endpoint.Subnet, info.Endpoint.Gateway = networkSubnet(nw)
+		}
+	}
+	return info, nil
+}
+
+func inspectNetwork(name string) (*NetworkInspect, error) {
+	nw, err := network.GetNetwork(name)
+	if err != nil {
+		return nil, fmt.Errorf("no such network: %s", name)
+	}
+	info := &NetworkInspect{
+		Name:       nw.Name,
+		Driver:     nw.Driver,
+		Labels:     nw.Labels,
+		Containers: []NetworkContainer{},
+	}
+	info.Subnet, info.Gateway = networkSubnet(nw)
+	containers, err := container.GetAllContainers()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, meta := range containers {
+		if meta.Network != nw.Name {
+			continue
+		}
+		info.Containers = append(info.Containers, NetworkContainer{ID: meta.ID, Name: meta.Name, IP: meta.IP})
+	}
+	return info, nil
+}
+
+func inspectImage(name string) (*ImageInspect, error) {
+	var path string
+	var stat os.FileInfo
+	for _, ext := range []string{".tar", ".tar.gz"} {
+		var err error
+		path = filepath.Join(config.ImagePath, name+ext)
+		if stat, err = os.Stat(path); err == nil {
+			break
+		}
+	}
+	if stat == nil {
+		return nil, fmt.Errorf("no such image: %s", name)
+	}
+	info := &ImageInspect{
+		Name:       name,
+		Path:       path,
+		Size:       stat.Size(),
+		CreateAt:   stat.ModTime(),
+		Containers: []string{},
+	}
+	containers, err := container.GetAllContainers()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, meta := range containers {
+		if meta.Image == name {
+			info.Containers = append(info.Containers, meta.Name)
+		}
+	}
+	return info, nil
+}
+
+// the subnet and the gateway of the network, the ip of the range is the gateway
+func networkSubnet(nw *network.NetWork) (string, string) {
+	if nw.IPRange == nil {
+		return "", ""
+	}
+	subnet := &net.IPNet{IP: nw.IPRange.IP.Mask(nw.IPRange.Mask), Mask: nw.IPRange.Mask}
+	return subnet.String(), nw.IPRange.IP.String()
+}
@@ -0,0 +1,312 @@
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mini-docker/container"
+	"mini-docker/logdriver"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// the time to wait for the client of the foreground container to attach
+	// before the exit code is dropped
+	attachWaitTimeout = 5 * time.Second
+	// the slow client is disconnected, so it can't block the container
+	attachWriteTimeout = 5 * time.Second
+	// the output beyond the size isn't replayed
+	maxReplaySize = 1 << 20
+)
+
+// the connection of an attached client
+type attachConn struct {
+	net.Conn
+	mu sync.Mutex
+}
+
+func (c *attachConn) writeFrame(stream byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.SetWriteDeadline(time.Now().Add(attachWriteTimeout))
+	return container.WriteFrame(c.Conn, stream, payload)
+}
+
+// the stdio hub of the container in the shim. the output of the container
+// is written to the log and all the attached clients, the input of the
+// clients is written to the container
+type ioHub struct {
+	tty         bool
+	interactive bool
+	listener    net.Listener
+	log         *logdriver.Writer
+
+	mu      sync.Mutex
+	clients map[*attachConn]struct{}
+	// the output before the first client attached, it's only kept for the
+	// foreground container whose client attaches after the container started
+	replay   *bytes.Buffer
+	attached chan struct{}
+	// the stdio of the current container process
+	stdin      io.WriteCloser
+	master     *os.File
+	childFiles []*os.File
+	pumps      sync.WaitGroup
+}
+
+// create the hub and listen on the attach socket of the container
+func newIOHub(opts *RunOptions) (*ioHub, error) {
+	dirPath := fmt.Sprintf(container.DefaultInfoPath, opts.Name)
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return nil, err
+	}
+	logDriver := opts.LogDriver
+	if logDriver == "" {
+		logDriver = container.JSONFileLogDriver
+	}
+	// the restarted container appends to the log
+	log, err := logdriver.New(logDriver, &logdriver.Info{
+		ContainerID:   opts.ID,
+		ContainerName: opts.Name,
+		LogPath:       filepath.Join(dirPath, container.ContainerLog),
+		Opts:          opts.LogOpts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sockPath := filepath.Join(dirPath, container.AttachSocket)
+	os.Remove(sockPath)
+	listener, err := net.Listen("unix", sockPath)
+	if err != nil {
+		log.Close()
+		return nil, fmt.Errorf("listen on %s error %v", sockPath, err)
+	}
+	hub := &ioHub{
+		tty:         opts.TTY,
+		interactive: opts.Interactive,
+		listener:    listener,
+		log:         log,
+		clients:     make(map[*attachConn]struct{}),
+	}
+	if !opts.Detach {
+		hub.replay = &bytes.Buffer{}
+		hub.attached = make(chan struct{})
+	}
+	go hub.accept()
+	return hub, nil
+}
+
+// set up the stdio of the container process before it starts
+func (h *ioHub) setup(cmd *exec.Cmd) error {
+	if h.tty {
+		master, slave, err := container.OpenPty()
+		if err != nil {
+			return fmt.Errorf("allocate pty error %v", err)
+		}
+		container.AttachPty(cmd, slave)
+		h.mu.Lock()
+		h.master = master
+		if h.interactive {
+			h.stdin = master
+		}
+		h.mu.Unlock()
+		h.childFiles = []*os.File{slave}
+		return nil
+	}
+
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	stderrReader, stderrWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+	h.childFiles = []*os.File{stdoutWriter, stderrWriter}
+	h.pumps.Add(2)
+	go h.pump(container.StreamStdout, stdoutReader)
+	go h.pump(container.StreamStderr, stderrReader)
+	if h.interactive {
+		stdinReader, stdinWriter, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		cmd.Stdin = stdinReader
+		h.childFiles = append(h.childFiles, stdinReader)
+		h.mu.Lock()
+		h.stdin = stdinWriter
+		h.mu.Unlock()
+	}
+	return nil
+}
+
+// start copying the output after the container process started, the output
+// ends after the container closes its ends of the stdio
+func (h *ioHub) start() {
+	for _, f := range h.childFiles {
+		f.Close()
+	}
+	h.childFiles = nil
+	if h.tty {
+		h.pumps.Add(1)
+		go h.pump(container.StreamStdout, h.master)
+	}
+}
+
+// wait for the rest output after the container process exited, and the
+// client of the foreground container
+func (h *ioHub) drain() {
+	h.pumps.Wait()
+	if err := h.log.Flush(); err != nil {
+		zap.L().Sugar().Warnf("write the container log error %v", err)
+	}
+	if h.attached != nil {
+		select {
+		case <-h.attached:
+		case <-time.After(attachWaitTimeout):
+		}
+	}
+}
+
+// send the exit code to the clients and disconnect them, it's called after
+// drain
+func (h *ioHub) finish(exitCode int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		c.writeFrame(container.StreamExit, container.EncodeExitCode(exitCode))
+		c.Close()
+	}
+	h.clients = make(map[*attachConn]struct{})
+	// only the first run of the foreground container is replayed
+	h.replay, h.attached = nil, nil
+	if h.stdin != nil {
+		h.stdin.Close()
+		h.stdin = nil
+	}
+	if h.master != nil {
+		h.master.Close()
+		h.master = nil
+	}
+}
+
+// stop listening, the socket is removed with the container
+func (h *ioHub) close() {
+	os.Remove(h.listener.Addr().String())
+	h.listener.Close()
+	h.log.Close()
+}
+
+func (h *ioHub) pump(stream byte, r *os.File) {
+	defer h.pumps.Done()
+	buf := make([]byte, 32*1024)
+	for {
+		// the pty master returns EIO after all the slaves are closed
+		n, err := r.Read(buf)
+		if n > 0 {
+			h.broadcast(stream, buf[:n])
+		}
+		if err != nil {
+			break
+		}
+	}
+	if !h.tty {
+		r.Close()
+	}
+}
+
+func (h *ioHub) broadcast(stream byte, data []byte) {
+	logStream := container.LogStreamStdout
+	if stream == container.StreamStderr {
+		logStream = container.LogStreamStderr
+	}
+	if err := h.log.WriteLog(logStream, data); err != nil {
+		zap.L().Sugar().Warnf("write the container log error %v", err)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.replay != nil && h.replay.Len() < maxReplaySize {
+		container.WriteFrame(h.replay, stream, data)
+	}
+	for c := range h.clients {
+		if err := c.writeFrame(stream, data); err != nil {
+			zap.L().Sugar().Warnf("write to the attached client error %v", err)
+			c.Close()
+			delete(h.clients, c)
+		}
+	}
+}
+
+func (h *ioHub) accept() {
+	for {
+		conn, err := h.listener.Accept()
+		if err != nil {
+			return
+		}
+		c := &attachConn{Conn: conn}
+		h.mu.Lock()
+		if h.replay != nil {
+			c.Write(h.replay.Bytes())
+			h.replay = nil
+			close(h.attached)
+		}
+		h.clients[c] = struct{}{}
+		h.mu.Unlock()
+		go h.serve(c)
+	}
+}
+
+// read the input and the window size from the client
+func (h *ioHub) serve(c *attachConn) {
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, c)
+		h.mu.Unlock()
+		c.Close()
+	}()
+	for {
+		stream, payload, err := container.ReadFrame(c)
+		if err != nil {
+			return
+		}
+		h.mu.Lock()
+		stdin, master := h.stdin, h.master
+		h.mu.Unlock()
+		switch stream {
+		case container.StreamStdin:
+			if stdin == nil {
+				continue
+			}
+			// the empty frame is EOF, the pty has no EOF
+			if len(payload) == 0 {
+				if !h.tty {
+					h.mu.Lock()
+					h.stdin = nil
+					h.mu.Unlock()
+					stdin.Close()
+				}
+				continue
+			}
+			if _, err := stdin.Write(payload); err != nil {
+				zap.L().Sugar().Warnf("write to the container stdin error %v", err)
+			}
+		case container.StreamResize:
+			ws, err := container.DecodeWinsize(payload)
+			if err != nil || master == nil {
+				continue
+			}
+			if err := container.SetWinsize(master.Fd(), ws); err != nil {
+				zap.L().Sugar().Warnf("set window size error %v", err)
+			}
+		}
+	}
+}
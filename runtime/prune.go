@@ -0,0 +1,59 @@
+package runtime
+
+import (
+	"fmt"
+	"mini-docker/container"
+	"mini-docker/network"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// SystemPrune remove the stopped containers, then the networks not used by any
+// container. only the objects matching the label filters are removed
+func SystemPrune(filters []string) error {
+	for _, filter := range filters {
+		if key, _, _ := strings.Cut(filter, "="); strings.ToLower(key) != "label" {
+			return fmt.Errorf("invalid filter %q, only the label filter is supported", filter)
+		}
+	}
+	f, err := container.ParseFilters(filters)
+	if err != nil {
+		return err
+	}
+	containers, err := container.GetAllContainers()
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("get all containers error %v", err)
+	}
+	removed := []string{}
+	for _, meta := range containers {
+		container.RefreshContainerStatus(meta)
+		if (meta.Status != container.STOP && meta.Status != container.EXIT) || !f.Match(meta) {
+			continue
+		}
+		if err := RemoveContainer(meta.Name); err != nil {
+			zap.L().Sugar().Warnf("remove container %s error %v", meta.Name, err)
+			continue
+		}
+		removed = append(removed, meta.ID)
+	}
+	if len(removed) > 0 {
+		fmt.Println("Deleted Containers:")
+		for _, id := range removed {
+			fmt.Println(id)
+		}
+	}
+
+	if err := network.Init(); err != nil {
+		return fmt.Errorf("network init error %v", err)
+	}
+	networks, err := network.PruneNetworks(filters)
+	if len(networks) > 0 {
+		fmt.Println("Deleted Networks:")
+		for _, name := range networks {
+			fmt.Println(name)
+		}
+	}
+	return err
+}
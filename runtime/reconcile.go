@@ -0,0 +1,439 @@
+package runtime
+
+import (
+	"fmt"
+	"mini-docker/cgroup"
+	"mini-docker/config"
+	"mini-docker/container"
+	"mini-docker/network"
+	"mini-docker/store"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	bootIDPath = "/proc/sys/kernel/random/boot_id"
+	// the schema version of boot.json
+	bootSchemaVersion = 1
+	// the container directory without config.json is being created by run
+	pendingContainerTimeout = time.Minute
+)
+
+// the boot id of the last reconciliation
+type bootState struct {
+	store.Versioned
+	BootID string `json:"boot_id"`
+}
+
+// the boot.json is kept next to the overlayfs of the containers, /var/run is
+// usually a tmpfs which doesn't survive the reboot
+var bootStore = store.New(filepath.Dir(config.ContainerPath), "%s.json", bootSchemaVersion, nil)
+
+// the stale state or the leaked resource found by the reconciliation
+type problem struct {
+	Kind   string
+	Object string
+	Detail string
+	Fixed  bool
+	Err    error
+	// repair or remove the stale state
+	fix func() error
+}
+
+// the status of the problem shown by system check
+func (p *problem) status() string {
+	switch {
+	case p.Err != nil:
+		return fmt.Sprintf("failed: %v", p.Err)
+	case p.Fixed:
+		return "fixed"
+	}
+	return "found"
+}
+
+type reconciler struct {
+	fix bool
+	// the automatic reconciliation on boot repairs the container states only,
+	// the leaked resources are removed by system check --fix
+	onBoot bool
+	// the host rebooted since the last reconciliation, no container process survives
+	rebooted   bool
+	containers []*container.ContainerMeta
+	// the containers whose process is alive
+	running  []*container.ContainerMeta
+	problems []*problem
+}
+
+// ReconcileOnBoot reconcile the state once after the host boots, it's called
+// before every command and does nothing until the next boot. the stale
+// containers are marked exited, the other problems are only logged
+func ReconcileOnBoot() error {
+	r := &reconciler{onBoot: true}
+	if err := r.reconcile(); err != nil {
+		return err
+	}
+	for _, p := range r.problems {
+		switch {
+		case p.Err != nil:
+			zap.L().Sugar().Warnf("reconcile %s %s: %s, %v", p.Kind, p.Object, p.Detail, p.Err)
+		case p.Fixed:
+			zap.L().Sugar().Infof("reconcile %s %s: %s", p.Kind, p.Object, p.Detail)
+		default:
+			zap.L().Sugar().Warnf("reconcile %s %s: %s, run system check --fix to repair it", p.Kind, p.Object, p.Detail)
+		}
+	}
+	return nil
+}
+
+// CheckSystem compare the recorded state with the live processes, mounts,
+// cgroups, veths and iptables rules, the stale ones are repaired or removed
+// if fix is true
+func CheckSystem(fix bool) error {
+	r := &reconciler{fix: fix}
+	if err := r.reconcile(); err != nil {
+		return err
+	}
+	if len(r.problems) == 0 {
+		fmt.Println("no problems found")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
+	fmt.Fprint(w, "KIND\tOBJECT\tPROBLEM\tSTATUS\n")
+	failed := 0
+	for _, p := range r.problems {
+		if p.Err != nil {
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Kind, p.Object, p.Detail, p.status())
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("flush error %v", err)
+	}
+	if !fix {
+		fmt.Println("run system check --fix to repair them")
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to fix %d problems", failed)
+	}
+	return nil
+}
+
+// the reconciliations are serialized by the lock of the boot state. the
+// containers are known stale if the boot id changes, otherwise by their pids.
+// the reconciliation on boot is skipped if it's done in this boot
+func (r *reconciler) reconcile() error {
+	data, err := os.ReadFile(bootIDPath)
+	if err != nil {
+		return fmt.Errorf("read boot id error %v", err)
+	}
+	bootID := strings.TrimSpace(string(data))
+	unlock, err := bootStore.Lock("boot")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	state, rebooted, err := loadBootState(bootID)
+	if err != nil {
+		return err
+	}
+	if r.onBoot && state.BootID == bootID {
+		return nil
+	}
+	r.rebooted = rebooted
+	if err := r.check(); err != nil {
+		return err
+	}
+	if !r.fix && !r.onBoot {
+		return nil
+	}
+	state.BootID = bootID
+	return bootStore.Write("boot", state)
+}
+
+// read the boot state of the last reconciliation, rebooted is true if the boot
+// id changed since then. the caller holds the lock of the boot state
+func loadBootState(bootID string) (*bootState, bool, error) {
+	state := &bootState{}
+	if err := bootStore.Get("boot", state); err != nil && !os.IsNotExist(err) {
+		return nil, false, err
+	}
+	// the state of the first run is checked by the pids
+	return state, state.BootID != "" && state.BootID != bootID, nil
+}
+
+// the later checks rely on the running containers found by checkContainers
+func (r *reconciler) check() error {
+	containers, err := container.GetAllContainers()
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("get all containers error %v", err)
+	}
+	r.containers = containers
+	if err := network.Init(); err != nil {
+		return err
+	}
+	r.checkContainers()
+	r.checkWorkSpaces()
+	r.checkCgroups()
+	r.checkNetworks()
+	r.checkIPAM()
+	r.checkNATRules()
+	// the networks of the containers are restored first
+	r.restartOnBoot()
+	return nil
+}
+
+// record the problem, which is fixed right away in the fix mode
+func (r *reconciler) report(kind, object, detail string, fix func() error) {
+	r.record(r.fix, kind, object, detail, fix)
+}
+
+// record the stale state, which is also repaired by the reconciliation on
+// boot. the repair restores the state, nothing is removed
+func (r *reconciler) repair(kind, object, detail string, fix func() error) {
+	r.record(r.fix || r.onBoot, kind, object, detail, fix)
+}
+
+func (r *reconciler) record(apply bool, kind, object, detail string, fix func() error) {
+	p := &problem{Kind: kind, Object: object, Detail: detail, fix: fix}
+	if apply {
+		p.Err = p.fix()
+		p.Fixed = p.Err == nil
+	}
+	r.problems = append(r.problems, p)
+}
+
+// the running container whose process is gone is marked exited
+func (r *reconciler) checkContainers() {
+	for _, meta := range r.containers {
+		var alive bool
+		switch meta.Status {
+		case container.RUNING, container.PAUSED:
+			alive = container.ProcessExists(meta.PID)
+		case container.RESTARTING:
+			// the shim restarts the container
+			alive = container.ProcessExists(meta.ShimPID)
+		default:
+			continue
+		}
+		if alive && !r.rebooted {
+			r.running = append(r.running, meta)
+			continue
+		}
+		detail := fmt.Sprintf("%s but the process %d is gone", meta.Status, meta.PID)
+		if r.rebooted {
+			detail = fmt.Sprintf("%s before the host rebooted", meta.Status)
+		}
+		meta := meta
+		r.repair("container", meta.Name, detail, func() error {
+			return container.MarkExited(meta, r.rebooted)
+		})
+	}
+}
+
+// the containers are started by their restart policies after the host reboots,
+// the exited containers are started too except the stopped ones of unless-stopped
+func (r *reconciler) restartOnBoot() {
+	if !r.rebooted {
+		return
+	}
+	for _, meta := range r.containers {
+		if !meta.RestartPolicy.ShouldRestartOnBoot(meta) {
+			continue
+		}
+		name := meta.Name
+		r.repair("container", name, fmt.Sprintf("restart by the %s policy after the host rebooted", meta.RestartPolicy), func() error {
+			_, err := startStopped(name, false)
+			return err
+		})
+	}
+}
+
+// the container directories without the container are removed, which are left
+// by the crashed run or the lost state after reboot
+func (r *reconciler) checkWorkSpaces() {
+	known := map[string]bool{}
+	for _, meta := range r.containers {
+		known[meta.Name] = true
+	}
+	infoRoot := filepath.Clean(fmt.Sprintf(container.DefaultInfoPath, ""))
+	entries, _ := os.ReadDir(infoRoot)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || known[name] {
+			continue
+		}
+		// the name is reserved by a running run command
+		if info, err := entry.Info(); err == nil && !r.rebooted && time.Since(info.ModTime()) < pendingContainerTimeout {
+			known[name] = true
+			continue
+		}
+		r.report("container", name, "the state directory has no config", func() error {
+			return container.DeleteConfig(name)
+		})
+	}
+
+	entries, _ = os.ReadDir(config.ContainerPath)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || known[name] {
+			continue
+		}
+		r.report("workspace", name, "the overlay directory of no container", func() error {
+			return container.RemoveWorkSpaceDir(name)
+		})
+	}
+}
+
+// the cgroups outlive the container processes if the shim is killed
+func (r *reconciler) checkCgroups() {
+	live := map[string]bool{}
+	for _, meta := range r.running {
+		live[meta.ID] = true
+	}
+	for _, id := range cgroup.ListContainerCgroups() {
+		if live[id] {
+			continue
+		}
+		path := cgroup.ContainerCgroupPath(id)
+		r.report("cgroup", path, "no running container uses the cgroup", func() error {
+			cgroup.NewCgroupManager(path).Destroy()
+			for _, left := range cgroup.ListContainerCgroups() {
+				if left == id {
+					return fmt.Errorf("the cgroup is busy")
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// the bridges are lost after reboot, the veths of the exited containers are
+// left if the shim is killed
+func (r *reconciler) checkNetworks() {
+	endpoints := map[string]bool{}
+	for _, meta := range r.running {
+		endpoints[network.EndPointDevice(meta.ID)] = true
+	}
+	for _, nw := range network.Networks() {
+		if !network.DeviceExists(nw) {
+			nw := nw
+			r.repair("network", nw.Name, fmt.Sprintf("the %s device is missing", nw.Driver), func() error {
+				return network.RestoreNetwork(nw)
+			})
+			continue
+		}
+		devices, err := network.EndPointDevices(nw)
+		if err != nil {
+			zap.L().Sugar().Warnf("list the devices of network %s error %v", nw.Name, err)
+			continue
+		}
+		for _, device := range devices {
+			if endpoints[device] {
+				continue
+			}
+			device := device
+			r.report("veth", device, fmt.Sprintf("no running container uses the veth on %s", nw.Name), func() error {
+				return network.DeleteDevice(device)
+			})
+		}
+	}
+}
+
+// the ips are kept until the container is removed, the gateway is kept until
+// the network is removed
+func (r *reconciler) checkIPAM() {
+	allocated, err := network.AllocatedIPs()
+	if err != nil {
+		zap.L().Sugar().Warnf("get the allocated ips error %v", err)
+		return
+	}
+	owners := map[string]bool{}
+	subnets := map[string]bool{}
+	for _, nw := range network.Networks() {
+		_, subnet, _ := net.ParseCIDR(nw.IPRange.String())
+		subnets[subnet.String()] = true
+		owners[nw.IPRange.IP.String()] = true
+	}
+	for _, meta := range r.containers {
+		if ip, _, err := net.ParseCIDR(meta.IP); err == nil {
+			owners[ip.String()] = true
+		}
+	}
+	for cidr, ips := range allocated {
+		_, subnet, _ := net.ParseCIDR(cidr)
+		if !subnets[cidr] {
+			r.report("ipam", cidr, "the subnet of no network", func() error {
+				return network.RemoveSubnet(subnet)
+			})
+			continue
+		}
+		for _, ip := range ips {
+			if owners[ip.String()] {
+				continue
+			}
+			ip := ip
+			r.report("ipam", ip.String(), "the ip of no container", func() error {
+				return network.ReleaseIP(subnet, ip)
+			})
+		}
+	}
+}
+
+// the port mappings of the exited containers and the masquerade rules of the
+// removed networks. only the port mappings to the mini-docker subnets are
+// checked, the other rules aren't created by mini-docker
+func (r *reconciler) checkNATRules() {
+	rules, err := network.NATRules()
+	if err != nil {
+		zap.L().Sugar().Warnf("list the nat rules error %v", err)
+		return
+	}
+	subnets := []*net.IPNet{}
+	bridges := map[string]bool{}
+	for _, nw := range network.Networks() {
+		_, subnet, _ := net.ParseCIDR(nw.IPRange.String())
+		subnets = append(subnets, subnet)
+		bridges[nw.Name] = true
+	}
+	for _, rule := range rules {
+		if rule.Subnet == nil || bridges[rule.Bridge] || network.DeviceExists(&network.NetWork{Name: rule.Bridge}) {
+			continue
+		}
+		subnets = append(subnets, rule.Subnet)
+		rule := rule
+		r.report("iptables", rule.String(), "the masquerade rule of no network", rule.Delete)
+	}
+
+	mappings := map[string]bool{}
+	for _, meta := range r.running {
+		ip, _, err := net.ParseCIDR(meta.IP)
+		if err != nil {
+			continue
+		}
+		for _, pm := range strings.Fields(meta.Port) {
+			mappings[pm+"@"+ip.String()] = true
+		}
+	}
+	for _, rule := range rules {
+		if rule.IP == nil || mappings[rule.HostPort+":"+rule.Port+"@"+rule.IP.String()] || !containsIP(subnets, rule.IP) {
+			continue
+		}
+		rule := rule
+		r.report("iptables", rule.String(), "the port mapping of no running container", rule.Delete)
+	}
+}
+
+func containsIP(subnets []*net.IPNet, ip net.IP) bool {
+	for _, subnet := range subnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package runtime
+
+import (
+	"mini-docker/container"
+	"mini-docker/store"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcileAfterReboot(t *testing.T) {
+	assert := assert.New(t)
+	old := bootStore
+	bootStore = store.New(t.TempDir(), "%s.json", bootSchemaVersion, nil)
+	defer func() { bootStore = old }()
+
+	// the first run has no boot state
+	_, rebooted, err := loadBootState("boot-2")
+	assert.Nil(err)
+	assert.False(rebooted)
+	assert.Nil(bootStore.Write("boot", &bootState{BootID: "boot-1"}))
+	state, rebooted, err := loadBootState("boot-2")
+	assert.Nil(err)
+	assert.True(rebooted)
+	assert.Equal("boot-1", state.BootID)
+
+	// the pid of the container before the reboot may be used by another process
+	meta := &container.ContainerMeta{Name: "web", Status: container.RUNING, PID: os.Getpid()}
+	r := &reconciler{rebooted: rebooted, containers: []*container.ContainerMeta{meta}}
+	r.checkContainers()
+	assert.Empty(r.running)
+	if assert.Len(r.problems, 1) {
+		assert.Equal(container.RUNING+" before the host rebooted", r.problems[0].Detail)
+		assert.False(r.problems[0].Fixed)
+	}
+
+	r = &reconciler{containers: []*container.ContainerMeta{meta}}
+	r.checkContainers()
+	assert.Len(r.running, 1)
+	assert.Empty(r.problems)
+
+	// the containers are started by the policies after the reboot
+	always := &container.ContainerMeta{Name: "db", Status: container.STOP, ManuallyStopped: true, RestartPolicy: &container.RestartPolicy{Name: container.RestartAlways}}
+	unlessStopped := &container.ContainerMeta{Name: "cache", Status: container.STOP, ManuallyStopped: true, RestartPolicy: &container.RestartPolicy{Name: container.RestartUnlessStopped}}
+	r = &reconciler{rebooted: true, containers: []*container.ContainerMeta{always, unlessStopped}}
+	r.restartOnBoot()
+	if assert.Len(r.problems, 1) {
+		assert.Equal("db", r.problems[0].Object)
+		assert.False(r.problems[0].Fixed)
+	}
+}
@@ -0,0 +1,19 @@
+package runtime
+
+import (
+	"fmt"
+	"mini-docker/container"
+	"mini-docker/network"
+)
+
+// RemoveContainer remove the stopped container, the ip is released only after
+// the status is checked, so the ip of the running container isn't reused
+func RemoveContainer(containerName string) error {
+	if _, err := container.GetStoppedContainer(containerName); err != nil {
+		return err
+	}
+	if err := network.DisConnect(containerName); err != nil {
+		return fmt.Errorf("release the ip of container %s error %v", containerName, err)
+	}
+	return container.RemoveContainer(containerName)
+}
@@ -1,83 +1,197 @@
 package runtime
 
 import (
+	"encoding/json"
+	"fmt"
 	"mini-docker/cgroup"
 	"mini-docker/cgroup/subsystems"
 	"mini-docker/container"
 	"mini-docker/network"
 	"os"
+	"os/exec"
 	"strings"
+	"syscall"
 
 	"go.uber.org/zap"
 )
 
-func Run(tty bool, args, env, volumePath, port []string, cfg *subsystems.ResourceConfig, imageName, containerName, net string) {
-	var containerID string = container.GenerateContainerId()
-	if containerName == "" {
-		containerName = containerID
+// the options of running a container, which are passed to the shim as json
+type RunOptions struct {
+	TTY         bool                       `json:"tty"`
+	Interactive bool                       `json:"interactive"`
+	Detach      bool                       `json:"detach"`
+	Args        []string                   `json:"args"`
+	Env         []string                   `json:"env"`
+	Volumes     []string                   `json:"volumes"`
+	Ports       []string                   `json:"ports"`
+	Resource    *subsystems.ResourceConfig `json:"resource"`
+	Image       string                     `json:"image"`
+	Name        string                     `json:"name"`
+	ID          string                     `json:"id"`
+	Network     string                     `json:"network"`
+	// the restart policy is enforced by the shim
+	RestartPolicy *container.RestartPolicy `json:"restart_policy"`
+	RestartCount  int                      `json:"restart_count"`
+	StopSignal    string                   `json:"stop_signal"`
+	Init          bool                     `json:"init"`
+	Workdir       string                   `json:"workdir"`
+	User          string                   `json:"user"`
+	Hostname      string                   `json:"hostname"`
+	AutoRemove    bool                     `json:"auto_remove"`
+	LogDriver     string                   `json:"log_driver"`
+	LogOpts       map[string]string        `json:"log_opts"`
+	Labels        map[string]string        `json:"labels"`
+	// the container is started again, its overlayfs is reused
+	Existing bool `json:"existing"`
+	// the detach keys are used by the client, not the shim
+	DetachKeys string `json:"-"`
+}
+
+// Run create the container supervised by a shim process, the terminal is
+// attached to the container unless it's detached, and the exit code of the
+// container is returned
+func Run(opts *RunOptions) (int, error) {
+	opts.ID = container.GenerateContainerId()
+	if opts.Name == "" {
+		opts.Name = opts.ID
+	}
+	// the foreground container with a tty is removed after it exits
+	opts.AutoRemove = opts.TTY && !opts.Detach
+	if err := container.ReserveName(opts.Name); err != nil {
+		return -1, err
+	}
+	if err := startShim(opts); err != nil {
+		removeFailedContainer(opts)
+		return -1, err
 	}
-	parent, writePipe, err := container.NewParentProcess(tty, imageName, containerName, env, volumePath)
+	if opts.Detach {
+		fmt.Println(opts.ID)
+		return 0, nil
+	}
+	return attachContainer(opts.Name, opts.DetachKeys)
+}
+
+// the started container process
+type containerProcess struct {
+	*exec.Cmd
+	cgroupManager *cgroup.CgroupManager
+}
+
+// start the container process, limit its resources and connect it to the network
+func startContainer(opts *RunOptions, hub *ioHub) (*containerProcess, error) {
+	parent, writePipe, err := container.NewParentProcess(opts.Image, opts.Name, opts.Volumes, opts.Existing)
 	if err != nil {
-		zap.L().Sugar().Errorf("new parent process error %v", err)
-		return
+		return nil, fmt.Errorf("new parent process error %v", err)
+	}
+	if err := hub.setup(parent); err != nil {
+		return nil, err
 	}
 	if err := parent.Start(); err != nil {
-		zap.L().Sugar().Errorf("parent process don't start. %v", err)
-		return
+		return nil, fmt.Errorf("parent process don't start. %v", err)
+	}
+	hub.start()
+	// every container owns a cgroup
+	cgroupManager := cgroup.NewCgroupManager(cgroup.ContainerCgroupPath(opts.ID))
+	// the container process blocks on the pipe until it gets the command
+	kill := func() {
+		parent.Process.Kill()
+		parent.Wait()
+		cgroupManager.Destroy()
+		hub.drain()
+		hub.finish(-1)
 	}
 	// record the container information
-	containerName, err = container.RecordContainer(parent.Process.Pid, args, volumePath, port, containerName, containerID, imageName)
-	if err != nil {
-		zap.L().Sugar().Error("record the container information error")
-		return
-	}
-	// set resource limit
-	cgroupManager := cgroup.NewCgroupManager("mini-docker")
-	defer cgroupManager.Destroy()
-	cgroupManager.Set(cfg)
-	cgroupManager.Apply(parent.Process.Pid)
+	containerMeta := &container.ContainerMeta{
+		PID:           parent.Process.Pid,
+		ID:            opts.ID,
+		Name:          opts.Name,
+		Command:       strings.Join(opts.Args, " "),
+		Args:          opts.Args,
+		Env:           opts.Env,
+		Volume:        strings.Join(opts.Volumes, " "),
+		Image:         opts.Image,
+		Port:          strings.Join(opts.Ports, " "),
+		Network:       opts.Network,
+		ShimPID:       os.Getpid(),
+		CgroupPath:    cgroupManager.Path,
+		Resource:      opts.Resource,
+		RestartPolicy: opts.RestartPolicy,
+		RestartCount:  opts.RestartCount,
+		StopSignal:    opts.StopSignal,
+		Init:          opts.Init,
+		Workdir:       opts.Workdir,
+		User:          opts.User,
+		Hostname:      opts.Hostname,
+		TTY:           opts.TTY,
+		Interactive:   opts.Interactive,
+		LogDriver:     opts.LogDriver,
+		LogOpts:       opts.LogOpts,
+		Labels:        opts.Labels,
+	}
+	if err := container.RecordContainer(containerMeta); err != nil {
+		kill()
+		return nil, fmt.Errorf("record the container information error %v", err)
+	}
+	// set resource limit, the container mustn't run without the requested limits
+	if err := cgroupManager.Set(opts.Resource); err != nil {
+		kill()
+		return nil, fmt.Errorf("set the resource limits error %v", err)
+	}
+	if err := cgroupManager.Apply(parent.Process.Pid); err != nil {
+		kill()
+		return nil, fmt.Errorf("apply the resource limits error %v", err)
+	}
 	// set network
-	if net != "" {
+	if opts.Network != "" {
 		if err := network.Init(); err != nil {
-			zap.L().Sugar().Errorf("init network error %v", err)
-			return 
+			kill()
+			return nil, fmt.Errorf("init network error %v", err)
 		}
-		containerMeta := &container.ContainerMeta{
-			PID:  parent.Process.Pid,
-			ID:   containerID,
-			Name: containerName,
-			Port: strings.Join(port, " "),
-		}
-		if err := network.Connect(net, containerMeta); err != nil {
-			zap.L().Sugar().Errorf("container connect network error %v", err)
-			return
+		if err := network.Connect(opts.Network, containerMeta); err != nil {
+			kill()
+			return nil, fmt.Errorf("container connect network error %v", err)
 		}
 	}
-	err = sendInitCMD(args, writePipe)
-	if err != nil {
-		zap.L().Sugar().Errorf("don't send command to child process. %v", err)
+	hostname := opts.Hostname
+	if hostname == "" {
+		hostname = opts.ID[:12]
 	}
-	if tty {
-		parent.Wait()
-		if err := network.DisConnect(containerName); err != nil {
-			zap.L().Sugar().Warnf("container network disconnect failed %v", err)
-		}
-		if err := container.DeleteConfig(containerName); err != nil {
-			zap.L().Sugar().Warnf("delete container config failed %v", err)
-		}
-		// delete overlayf
-		container.DeleteWorkSpace(imageName, containerName, volumePath)
+	initConfig := &container.InitConfig{
+		Args: opts.Args,
+		// the container inherits the environment of mini-docker
+		Env:      append(os.Environ(), opts.Env...),
+		Cwd:      opts.Workdir,
+		User:     opts.User,
+		Hostname: hostname,
+		Mounts:   container.DefaultMounts(),
+		Init:     opts.Init,
 	}
-}
-
-// send command to child process(container)
-func sendInitCMD(args []string, w *os.File) error {
-	cmd := strings.Join(args, " ")
-	zap.L().Sugar().Infof("the total command is %s", cmd)
-	_, err := w.WriteString(cmd)
+	err = sendInitConfig(initConfig, writePipe)
 	if err != nil {
-		return err
+		zap.L().Sugar().Errorf("don't send init config to child process. %v", err)
 	}
+	return &containerProcess{Cmd: parent, cgroupManager: cgroupManager}, nil
+}
+
+// send the init config to child process(container)
+func sendInitConfig(cfg *container.InitConfig, w *os.File) error {
 	defer w.Close()
-	return nil
+	zap.L().Sugar().Infof("the total command is %q", cfg.Args)
+	return json.NewEncoder(w).Encode(cfg)
+}
+
+// wait for the container process to exit, return the exit code and whether
+// the oom killer killed it
+func waitContainer(process *containerProcess) (int, bool) {
+	process.Wait()
+	exitCode := -1
+	if status, ok := process.ProcessState.Sys().(syscall.WaitStatus); ok {
+		if status.Signaled() {
+			// the shell convention, 128 + signal number
+			exitCode = 128 + int(status.Signal())
+		} else {
+			exitCode = status.ExitStatus()
+		}
+	}
+	return exitCode, process.cgroupManager.OOMKilled()
 }
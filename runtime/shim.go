@@ -0,0 +1,211 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mini-docker/cgroup"
+	"mini-docker/cgroup/subsystems"
+	"mini-docker/container"
+	"mini-docker/network"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// the file descriptors of the shim, 0-2 are stdio
+const (
+	shimOptionsFd = 3
+	shimReadyFd   = 4
+)
+
+// start the shim of the detached container and wait until the container
+// is started, the options are written to the shim through a pipe and the
+// shim reports the start error through another pipe
+func startShim(opts *RunOptions) error {
+	optsReader, optsWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readyReader.Close()
+
+	cmd := exec.Command("/proc/self/exe", "shim")
+	// the shim leaves the session of the terminal, it outlives the cli
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	cmd.ExtraFiles = []*os.File{optsReader, readyWriter}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start shim error %v", err)
+	}
+	optsReader.Close()
+	readyWriter.Close()
+
+	if err := json.NewEncoder(optsWriter).Encode(opts); err != nil {
+		optsWriter.Close()
+		return fmt.Errorf("send options to shim error %v", err)
+	}
+	optsWriter.Close()
+	// the shim closes the pipe without any message after the container started
+	msg, err := io.ReadAll(readyReader)
+	if err != nil {
+		return fmt.Errorf("read shim error %v", err)
+	}
+	if len(msg) != 0 {
+		return fmt.Errorf("start container error: %s", msg)
+	}
+	return cmd.Process.Release()
+}
+
+// Shim supervise the detached container, it's the parent of the container
+// process, so it can reap the container and record the exit status
+func Shim() error {
+	// the container process mustn't inherit the pipes, otherwise the cli can't
+	// read EOF from the ready pipe until the container exits
+	syscall.CloseOnExec(shimOptionsFd)
+	syscall.CloseOnExec(shimReadyFd)
+	optsPipe := os.NewFile(uintptr(shimOptionsFd), "options")
+	readyPipe := os.NewFile(uintptr(shimReadyFd), "ready")
+	opts := &RunOptions{}
+	err := json.NewDecoder(optsPipe).Decode(opts)
+	optsPipe.Close()
+	if err != nil {
+		readyPipe.WriteString(fmt.Sprintf("decode options error %v", err))
+		readyPipe.Close()
+		return err
+	}
+
+	// the hub owns the stdio of the container and serves the attached clients
+	hub, err := newIOHub(opts)
+	if err != nil {
+		readyPipe.WriteString(err.Error())
+		readyPipe.Close()
+		removeFailedContainer(opts)
+		return err
+	}
+	defer hub.close()
+	process, err := startContainer(opts, hub)
+	if err != nil {
+		// the foreground container is removed before the cli returns
+		if opts.AutoRemove {
+			container.DeleteWorkSpace(opts.Image, opts.Name, opts.Volumes)
+		}
+		removeFailedContainer(opts)
+		readyPipe.WriteString(err.Error())
+		readyPipe.Close()
+		return err
+	}
+	readyPipe.Close()
+	// the restarted container reuses its overlayfs
+	opts.Existing = true
+
+	// restart the exited container by the restart policy
+	delay := time.Duration(0)
+	for {
+		startAt := time.Now()
+		exitCode, oomKilled := waitContainer(process)
+		zap.L().Sugar().Infof("container %s exited with code %d", opts.Name, exitCode)
+		// the log is complete when the exit is recorded, so the log follower
+		// can stop after the container exited
+		hub.drain()
+		if err := container.RecordExit(opts.Name, exitCode, oomKilled); err != nil {
+			zap.L().Sugar().Errorf("record the exit of container %s error %v", opts.Name, err)
+		}
+		cleanupContainer(opts.Name, process.cgroupManager)
+		// the client returns after the container is removed
+		if opts.AutoRemove {
+			removeContainer(opts)
+			hub.finish(exitCode)
+			return nil
+		}
+		hub.finish(exitCode)
+
+		delay = container.RestartDelay(delay, time.Since(startAt))
+		if !waitRestart(opts, delay) {
+			return nil
+		}
+		opts.RestartCount++
+		zap.L().Sugar().Infof("restart container %s, restart count %d", opts.Name, opts.RestartCount)
+		process, err = startContainer(opts, hub)
+		if err != nil {
+			if err := container.RecordExit(opts.Name, -1, false); err != nil {
+				zap.L().Sugar().Errorf("record the exit of container %s error %v", opts.Name, err)
+			}
+			return err
+		}
+	}
+}
+
+// wait for the delay before restarting the container, return false if the
+// container shouldn't be restarted or it's stopped or removed during the delay
+func waitRestart(opts *RunOptions, delay time.Duration) bool {
+	meta, err := container.GetContainerByName(opts.Name)
+	if err != nil || !meta.RestartPolicy.ShouldRestart(meta) {
+		return false
+	}
+	if err := container.MarkRestarting(meta); err != nil {
+		zap.L().Sugar().Errorf("mark container %s restarting error %v", opts.Name, err)
+		return false
+	}
+	deadline := time.Now().Add(delay)
+	for time.Now().Before(deadline) {
+		time.Sleep(min(100*time.Millisecond, time.Until(deadline)))
+		meta, err := container.GetContainerByName(opts.Name)
+		if err != nil || meta.Status != container.RESTARTING {
+			return false
+		}
+	}
+	// the container may be updated during the delay
+	meta, err = container.GetContainerByName(opts.Name)
+	if err != nil || meta.Status != container.RESTARTING || meta.ManuallyStopped {
+		return false
+	}
+	reloadOptions(opts, meta)
+	return true
+}
+
+// apply the settings changed while the container runs to the options of the
+// restarted container, otherwise the restart records the stale settings the
+// shim got at startup, e.g. the limits before update
+func reloadOptions(opts *RunOptions, meta *container.ContainerMeta) {
+	opts.Resource = meta.Resource
+	if opts.Resource == nil {
+		opts.Resource = &subsystems.ResourceConfig{}
+	}
+	opts.RestartPolicy = meta.RestartPolicy
+}
+
+// release the resources of the exited container, the ip and the overlayfs are
+// kept until the container is removed
+func cleanupContainer(containerName string, cgroupManager *cgroup.CgroupManager) {
+	if err := network.RemovePortMapping(containerName); err != nil {
+		zap.L().Sugar().Warnf("container remove port mapping failed %v", err)
+	}
+	cgroupManager.Destroy()
+}
+
+// remove the network, the config and the overlayfs of the foreground container
+func removeContainer(opts *RunOptions) {
+	if err := network.DisConnect(opts.Name); err != nil {
+		zap.L().Sugar().Warnf("container network disconnect failed %v", err)
+	}
+	if err := container.DeleteConfig(opts.Name); err != nil {
+		zap.L().Sugar().Warnf("delete container config failed %v", err)
+	}
+	container.DeleteWorkSpace(opts.Image, opts.Name, opts.Volumes)
+}
+
+// remove the directory of the container that failed to start, unless it's
+// the stopped container started again
+func removeFailedContainer(opts *RunOptions) {
+	cfgPath := filepath.Join(fmt.Sprintf(container.DefaultInfoPath, opts.Name), container.ConfigName)
+	if _, err := os.Stat(cfgPath); opts.AutoRemove || os.IsNotExist(err) {
+		container.DeleteConfig(opts.Name)
+	}
+}
@@ -0,0 +1,30 @@
+package runtime
+
+import (
+	"mini-docker/cgroup/subsystems"
+	"mini-docker/container"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloadOptions(t *testing.T) {
+	assert := assert.New(t)
+	opts := &RunOptions{
+		Resource:      &subsystems.ResourceConfig{MemoryLimit: "100m"},
+		RestartPolicy: &container.RestartPolicy{Name: container.RestartAlways},
+	}
+	// the limits are updated between the restarts
+	meta := &container.ContainerMeta{
+		Resource:      &subsystems.ResourceConfig{MemoryLimit: "200m", Cpus: "0.5"},
+		RestartPolicy: &container.RestartPolicy{Name: container.RestartOnFailure, MaximumRetryCount: 3},
+	}
+	reloadOptions(opts, meta)
+	assert.Equal("200m", opts.Resource.MemoryLimit)
+	assert.Equal("0.5", opts.Resource.Cpus)
+	assert.Equal(container.RestartOnFailure, opts.RestartPolicy.Name)
+
+	reloadOptions(opts, &container.ContainerMeta{})
+	assert.NotNil(opts.Resource)
+	assert.True(opts.RestartPolicy.IsNone())
+}
@@ -0,0 +1,98 @@
+package runtime
+
+import (
+	"fmt"
+	"mini-docker/cgroup/subsystems"
+	"mini-docker/container"
+	"strings"
+	"time"
+)
+
+// StartContainer start the stopped container with its recorded options, the
+// overlayfs and the ip of the container are reused. the terminal is attached
+// to the container if attach is true, and the exit code of the container is
+// returned
+func StartContainer(containerName string, attach bool, detachKeys string) (int, error) {
+	opts, err := startStopped(containerName, attach)
+	if err != nil {
+		return -1, err
+	}
+	if attach {
+		return attachContainer(opts.Name, detachKeys)
+	}
+	fmt.Println(opts.Name)
+	return 0, nil
+}
+
+// start the stopped container by a new shim
+func startStopped(containerName string, attach bool) (*RunOptions, error) {
+	meta, err := container.GetContainerByName(containerName)
+	if err != nil {
+		return nil, fmt.Errorf("get container meta by container name error %v", err)
+	}
+	container.RefreshContainerStatus(meta)
+	if meta.Status != container.STOP && meta.Status != container.EXIT {
+		return nil, fmt.Errorf("container %s is %s, only the stopped container can be started", containerName, meta.Status)
+	}
+	// the old shim may be still cleaning up the container
+	if !container.WaitProcessExit(meta.ShimPID, container.DefaultStopTimeout) {
+		return nil, fmt.Errorf("the previous process of container %s doesn't exit", containerName)
+	}
+	// the shim has recorded the exit
+	meta, err = container.GetContainerByName(containerName)
+	if err != nil {
+		return nil, fmt.Errorf("get container meta by container name error %v", err)
+	}
+
+	opts := &RunOptions{
+		TTY:           meta.TTY,
+		Interactive:   meta.Interactive,
+		Detach:        !attach,
+		Args:          meta.Args,
+		Env:           meta.Env,
+		Volumes:       strings.Fields(meta.Volume),
+		Ports:         strings.Fields(meta.Port),
+		Resource:      meta.Resource,
+		Image:         meta.Image,
+		Name:          meta.Name,
+		ID:            meta.ID,
+		Network:       meta.Network,
+		RestartPolicy: meta.RestartPolicy,
+		StopSignal:    meta.StopSignal,
+		Init:          meta.Init,
+		Workdir:       meta.Workdir,
+		User:          meta.User,
+		Hostname:      meta.Hostname,
+		LogDriver:     meta.LogDriver,
+		LogOpts:       meta.LogOpts,
+		Labels:        meta.Labels,
+		Existing:      true,
+	}
+	// the container created by an old version only records the command
+	if len(opts.Args) == 0 {
+		opts.Args = strings.Fields(meta.Command)
+	}
+	if opts.Resource == nil {
+		opts.Resource = &subsystems.ResourceConfig{}
+	}
+	if err := startShim(opts); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// RestartContainer stop the container if it's running, then start it again
+func RestartContainer(containerName string, timeout time.Duration) error {
+	meta, err := container.GetContainerByName(containerName)
+	if err != nil {
+		return fmt.Errorf("get container meta by container name error %v", err)
+	}
+	container.RefreshContainerStatus(meta)
+	if meta.Status == container.RUNING || meta.Status == container.PAUSED || meta.Status == container.RESTARTING {
+		if err := container.StopContainer(containerName, timeout); err != nil {
+			return err
+		}
+	}
+	_, err = StartContainer(containerName, false, "")
+	return err
+}
@@ -0,0 +1,227 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Object the object in the store, it's usually implemented by embedding Versioned
+type Object interface {
+	GetSchemaVersion() int
+	SetSchemaVersion(version int)
+}
+
+// Versioned the schema version of the stored object
+type Versioned struct {
+	SchemaVersion int `json:"schema_version"`
+}
+
+func (v *Versioned) GetSchemaVersion() int {
+	return v.SchemaVersion
+}
+
+func (v *Versioned) SetSchemaVersion(version int) {
+	v.SchemaVersion = version
+}
+
+// MigrateFunc upgrade the stored data of the old version to the next version
+type MigrateFunc func(version int, data []byte) ([]byte, error)
+
+// Store keep the objects as json files under the root, e.g. <root>/<key>/config.json.
+// a file is replaced by renaming a temporary file, so the readers never see a
+// partial file even if the writer crashes. the updates of an object are
+// serialized by the file lock, so the concurrent commands don't lose updates
+type Store struct {
+	root string
+	// the path of the object relative to the root, %s is the key
+	layout  string
+	version int
+	migrate MigrateFunc
+}
+
+// New create the store, the objects of the old version are upgraded by migrate
+// when they're read
+func New(root, layout string, version int, migrate MigrateFunc) *Store {
+	return &Store{root: root, layout: layout, version: version, migrate: migrate}
+}
+
+// Path get the file path of the object
+func (s *Store) Path(key string) string {
+	return filepath.Join(s.root, fmt.Sprintf(s.layout, key))
+}
+
+// Keys list the keys of the stored objects
+func (s *Store) Keys() ([]string, error) {
+	prefix, suffix, _ := strings.Cut(filepath.Join(s.root, s.layout), "%s")
+	paths, err := filepath.Glob(prefix + "*" + suffix)
+	if err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	for _, path := range paths {
+		key := strings.TrimSuffix(strings.TrimPrefix(path, prefix), suffix)
+		if key != "" && !strings.Contains(key, string(filepath.Separator)) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Get read the object, the error satisfies os.IsNotExist if it doesn't exist
+func (s *Store) Get(key string, v Object) error {
+	data, err := os.ReadFile(s.Path(key))
+	if err != nil {
+		return err
+	}
+	// the object of version 0 may not be a json object
+	header := &Versioned{}
+	json.Unmarshal(data, header)
+	version := header.SchemaVersion
+	if version > s.version {
+		return fmt.Errorf("the schema version %d of %s is newer than %d", version, s.Path(key), s.version)
+	}
+	for ; version < s.version && s.migrate != nil; version++ {
+		if data, err = s.migrate(version, data); err != nil {
+			return fmt.Errorf("migrate %s from version %d error %v", s.Path(key), version, err)
+		}
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("unmarshal %s error %v", s.Path(key), err)
+	}
+	v.SetSchemaVersion(s.version)
+	return nil
+}
+
+// Put write the object with the lock held
+func (s *Store) Put(key string, v Object) error {
+	unlock, err := s.Lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.Write(key, v)
+}
+
+// Update read the object, change it by fn and write it back with the lock
+// held. v keeps its value if the object doesn't exist, the object isn't
+// written if fn returns an error
+func (s *Store) Update(key string, v Object, fn func() error) error {
+	unlock, err := s.Lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := s.Get(key, v); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	return s.Write(key, v)
+}
+
+// Delete remove the object with the lock held
+func (s *Store) Delete(key string) error {
+	unlock, err := s.Lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := os.Remove(s.Path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(s.lockPath(key))
+}
+
+// Lock take the exclusive lock of the object, the directory of the object must
+// exist except the root. the lock file is opened again if it's removed while
+// waiting for the lock
+func (s *Store) Lock(key string) (func(), error) {
+	if err := os.MkdirAll(s.root, 0755); err != nil {
+		return nil, fmt.Errorf("mkdir dir %s error %v", s.root, err)
+	}
+	for {
+		f, err := os.OpenFile(s.lockPath(key), os.O_CREATE|os.O_RDWR|syscall.O_CLOEXEC, 0644)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+			if err != syscall.EINTR {
+				break
+			}
+		}
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("lock %s error %v", f.Name(), err)
+		}
+		// the lock file is removed by Delete or with the directory of the object
+		// while waiting, the lock of the removed file locks nothing
+		if sameFile(f) {
+			return func() {
+				syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+				f.Close()
+			}, nil
+		}
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}
+}
+
+// check whether the opened file is still the file at its path
+func sameFile(f *os.File) bool {
+	opened, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(f.Name())
+	return err == nil && os.SameFile(opened, current)
+}
+
+// Write replace the file of the object atomically, the caller should hold
+// the lock of the object
+func (s *Store) Write(key string, v Object) error {
+	v.SetSchemaVersion(s.version)
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal %s error %v", key, err)
+	}
+	path := s.Path(key)
+	dir := filepath.Dir(path)
+	// every writer has its own temporary file
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temporary file error %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	// the data is on the disk before the rename
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write file %s error %v", tmp.Name(), err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename file %s error %v", tmp.Name(), err)
+	}
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+func (s *Store) lockPath(key string) string {
+	return s.Path(key) + ".lock"
+}
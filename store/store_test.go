@@ -0,0 +1,120 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testObject struct {
+	Versioned
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestStore(t *testing.T) {
+	assert := assert.New(t)
+	root := t.TempDir()
+	s := New(root, "%s/config.json", 1, nil)
+	os.Mkdir(filepath.Join(root, "a"), 0755)
+	os.Mkdir(filepath.Join(root, "b"), 0755)
+
+	obj := &testObject{}
+	assert.True(os.IsNotExist(s.Get("a", obj)))
+	assert.Nil(s.Put("a", &testObject{Name: "a"}))
+	assert.Nil(s.Get("a", obj))
+	assert.Equal("a", obj.Name)
+	assert.Equal(1, obj.SchemaVersion)
+
+	// the updates of the goroutines aren't lost
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			obj := &testObject{}
+			assert.Nil(s.Update("b", obj, func() error {
+				obj.Count++
+				return nil
+			}))
+		}()
+	}
+	wg.Wait()
+	assert.Nil(s.Get("b", obj))
+	assert.Equal(20, obj.Count)
+
+	keys, err := s.Keys()
+	assert.Nil(err)
+	assert.ElementsMatch([]string{"a", "b"}, keys)
+
+	assert.Nil(s.Delete("a"))
+	keys, _ = s.Keys()
+	assert.Equal([]string{"b"}, keys)
+	// only the config files are left
+	files, _ := os.ReadDir(filepath.Join(root, "b"))
+	assert.Len(files, 2)
+}
+
+func TestStoreVersion(t *testing.T) {
+	assert := assert.New(t)
+	root := t.TempDir()
+	// the version 0 stores the name only
+	os.WriteFile(filepath.Join(root, "old.json"), []byte(`"old"`), 0644)
+	os.WriteFile(filepath.Join(root, "new.json"), []byte(`{"schema_version":3}`), 0644)
+	s := New(root, "%s.json", 2, func(version int, data []byte) ([]byte, error) {
+		if version == 0 {
+			var name string
+			if err := json.Unmarshal(data, &name); err != nil {
+				return nil, err
+			}
+			return json.Marshal(&testObject{Name: name})
+		}
+		return data, nil
+	})
+	obj := &testObject{}
+	assert.Nil(s.Get("old", obj))
+	assert.Equal("old", obj.Name)
+	assert.Equal(2, obj.SchemaVersion)
+	assert.NotNil(s.Get("new", obj))
+}
+
+func TestLockRemovedFile(t *testing.T) {
+	assert := assert.New(t)
+	root := t.TempDir()
+	s := New(root, "%s/config.json", 1, nil)
+	os.Mkdir(filepath.Join(root, "a"), 0755)
+
+	unlock, err := s.Lock("a")
+	assert.Nil(err)
+	locked := make(chan func())
+	go func() {
+		unlock, err := s.Lock("a")
+		assert.Nil(err)
+		locked <- unlock
+	}()
+	time.Sleep(50 * time.Millisecond)
+	// the lock file is removed with the directory while the waiter blocks on it
+	os.Remove(s.lockPath("a"))
+	unlockNew, err := s.Lock("a")
+	assert.Nil(err)
+	unlock()
+
+	// the waiter locks the new lock file
+	select {
+	case <-locked:
+		t.Fatal("the lock is held twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlockNew()
+	select {
+	case unlock := <-locked:
+		unlock()
+	case <-time.After(time.Second):
+		t.Fatal("the waiter isn't woken up")
+	}
+}